@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+
+	"bot/storage"
+	"bot/watcher"
+)
+
+// adminServer is the configuration of a server without its credentials, the webhook token, the events secret,
+// the api token and the mirror targets, which can be webhook urls, are only reported as set or not.
+type adminServer struct {
+	storage.Server
+	HasWebhookToken bool            `json:"has_webhook_token"`
+	HasEventsSecret bool            `json:"has_events_secret"`
+	HasApiToken     bool            `json:"has_api_token"`
+	MirrorPlatforms []string        `json:"mirror_platforms,omitempty"`
+	Watcher         *watcher.Status `json:"watcher,omitempty"`
+}
+
+type adminAPI struct {
+	store *storage.Store
+	w     *watcher.Watcher
+}
+
+func registerAdminAPI(mux *http.ServeMux, token string, store *storage.Store, w *watcher.Watcher) {
+	api := &adminAPI{store: store, w: w}
+	mux.Handle("GET /admin/servers", requireToken(token, http.HandlerFunc(api.listServers)))
+	mux.Handle("GET /admin/servers/{id}", requireToken(token, http.HandlerFunc(api.getServer)))
+	mux.Handle("GET /admin/watchers", requireToken(token, http.HandlerFunc(api.listWatchers)))
+	mux.Handle("POST /admin/servers/{id}/refresh", requireToken(token, http.HandlerFunc(api.refreshServer)))
+	mux.Handle("POST /admin/servers/{id}/unwatch", requireToken(token, http.HandlerFunc(api.unwatchServer)))
+	mux.Handle("DELETE /admin/servers/{id}", requireToken(token, http.HandlerFunc(api.purgeServer)))
+}
+
+func (api *adminAPI) listServers(w http.ResponseWriter, r *http.Request) {
+	servers, err := api.store.ListServers()
+	if err != nil {
+		slog.Error("error listing servers", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminServer, 0, len(servers))
+	for _, srv := range servers {
+		out = append(out, api.withStatus(srv))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (api *adminAPI) getServer(w http.ResponseWriter, r *http.Request) {
+	srv, err := api.store.ReadServer(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if srv == nil {
+		http.Error(w, "server not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, api.withStatus(*srv))
+}
+
+func (api *adminAPI) listWatchers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, api.w.Statuses())
+}
+
+func (api *adminAPI) refreshServer(w http.ResponseWriter, r *http.Request) {
+	serverId := r.PathValue("id")
+	if !api.w.Refresh(serverId) {
+		http.Error(w, "server is not watched", http.StatusNotFound)
+		return
+	}
+	slog.Info("forced refresh via admin api", slog.String("server", serverId))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (api *adminAPI) unwatchServer(w http.ResponseWriter, r *http.Request) {
+	serverId := r.PathValue("id")
+	slog.Info("stopping watcher via admin api", slog.String("server", serverId))
+	api.w.Unwatch(serverId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *adminAPI) purgeServer(w http.ResponseWriter, r *http.Request) {
+	serverId := r.PathValue("id")
+	slog.Info("purging server via admin api", slog.String("server", serverId))
+	api.w.Unwatch(serverId)
+	if err := api.store.DeleteServer(serverId); err != nil {
+		slog.Error("error deleting server", slog.String("server", serverId), "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *adminAPI) withStatus(srv storage.Server) adminServer {
+	out := adminServer{
+		HasWebhookToken: srv.WebhookToken != "",
+		HasEventsSecret: srv.EventsSecret != "",
+		HasApiToken:     srv.ApiToken != "",
+		MirrorPlatforms: slices.Sorted(maps.Keys(srv.Mirrors)),
+	}
+	srv.WebhookToken, srv.EventsSecret, srv.ApiToken, srv.Mirrors = "", "", "", nil
+	out.Server = srv
+	if status, ok := api.w.Status(srv.ServerId); ok {
+		out.Watcher = &status
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
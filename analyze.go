@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"bot/i18n"
+	"bot/storage"
+	"bot/warcraftlogs"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const analyzeMenuPrefix = "analyze:"
+
+func wowAnalyzerURL(reportCode string, fightId, sourceId int) string {
+	return fmt.Sprintf("https://wowanalyzer.com/report/%v/%d/%d", reportCode, fightId, sourceId)
+}
+
+// constructAnalyzeMenu offers the kills of the report, picking one lists WoWAnalyzer links of its players.
+func constructAnalyzeMenu(locale discordgo.Locale, reportCode string, kills []warcraftlogs.Fight) discordgo.ActionsRow {
+	options := make([]discordgo.SelectMenuOption, 0, min(len(kills), 25))
+	seen := make(map[string]int)
+	for _, f := range kills[:min(len(kills), 25)] {
+		seen[f.Name]++
+		label := f.Name
+		if seen[f.Name] > 1 {
+			label = fmt.Sprintf("%v (%d)", f.Name, seen[f.Name])
+		}
+		options = append(options, discordgo.SelectMenuOption{Label: label, Value: strconv.Itoa(f.ID)})
+	}
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    analyzeMenuPrefix + reportCode,
+				Placeholder: i18n.T(locale, "analyze.placeholder"),
+				Options:     options,
+			},
+		},
+	}
+}
+
+func killFights(fights []warcraftlogs.Fight) []warcraftlogs.Fight {
+	return slices.DeleteFunc(slices.Clone(fights), func(f warcraftlogs.Fight) bool { return !f.Kill })
+}
+
+// handleAnalyze answers a pick in the analyze menu with the WoWAnalyzer links of the fight,
+// the characters claimed by the user come first.
+func handleAnalyze(s *discordgo.Session, i *discordgo.InteractionCreate, wlClient *warcraftlogs.Client, store *storage.Store) {
+	data := i.MessageComponentData()
+	reportCode := strings.TrimPrefix(data.CustomID, analyzeMenuPrefix)
+	if len(data.Values) == 0 {
+		return
+	}
+	fightId, err := strconv.Atoi(data.Values[0])
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	name, players, err := wlClient.FightPlayers(ctx, reportCode, fightId)
+	if err != nil {
+		slog.Error("error loading fight players", slog.String("server", i.GuildID), slog.String("report", reportCode), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+
+	claims, _ := store.ReadClaims(i.GuildID)
+	userId := interactionUserId(i)
+	own := func(a warcraftlogs.Actor) bool { return userId != "" && claims[strings.ToLower(a.Name)] == userId }
+	slices.SortFunc(players, func(a, b warcraftlogs.Actor) int {
+		if own(a) != own(b) {
+			if own(a) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var sb strings.Builder
+	sb.WriteString("💡 " + i18n.T(i.Locale, "analyze.title", name))
+	for _, p := range players {
+		link := fmt.Sprintf("[%v](<%v>)", p.Name, wowAnalyzerURL(reportCode, fightId, p.ID))
+		if own(p) {
+			link = "**" + link + "**"
+		}
+		// messages are limited to 2000 characters, a raid of 30 fits
+		if sb.Len()+len(link)+1 > 2000 {
+			break
+		}
+		sb.WriteString("\n" + link)
+	}
+	respond(s, i, sb.String())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bot/mechanics"
+	"bot/warcraftlogs"
+	"bot/watcher"
+)
+
+const maxOffenders = 3
+
+// mechanicOffenders returns the players who took the most avoidable damage in the boss fights of the report
+// that have mechanics defined, fights of other encounters cost no requests. A fight that fails to load is left out
+// and its error returned along with the offenders of the other fights.
+func mechanicOffenders(ctx context.Context, wlClient *warcraftlogs.Client, se watcher.StatsEvent) ([]mechanics.Offender, error) {
+	fights, err := wlClient.GetBossFights(ctx, se.ReportId)
+	if err != nil {
+		return nil, err
+	}
+	offenders := make(map[string]*mechanics.Offender)
+	var errs []error
+	for _, f := range fights {
+		enc, ok := mechanics.Lookup(f.EncounterID)
+		if !ok {
+			continue
+		}
+		events, err := wlClient.AbilityEvents(ctx, se.ReportId, f.ID, se.Server.WipeCutoff, enc.FilterExpression())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fight %v: %w", f.ID, err))
+			continue
+		}
+		enc.Tally(events, offenders)
+	}
+	return mechanics.Worst(offenders, maxOffenders), errors.Join(errs...)
+}
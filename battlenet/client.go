@@ -0,0 +1,312 @@
+package battlenet
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+const (
+	authorizeURL = "https://oauth.battle.net/authorize"
+	tokenURL     = "https://oauth.battle.net/token"
+	characterURL = "https://%v.api.blizzard.com/profile/wow/character/%v/%v"
+	accountURL   = "https://%v.api.blizzard.com/profile/user/wow"
+)
+
+type Member struct {
+	Name  string
+	Realm string
+	Level int
+	Rank  int
+}
+
+type Roster struct {
+	Members []Member
+}
+
+// Named returns the members with the name, names are compared case-insensitively.
+// Guilds of connected realms can have the same name on several realms.
+func (r Roster) Named(name string) []Member {
+	var found []Member
+	for _, m := range r.Members {
+		if strings.EqualFold(m.Name, name) {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+// Find returns the member with the name on the realm slug, without a realm the name has to be unique in the roster.
+func (r Roster) Find(name, realm string) (Member, bool) {
+	var found []Member
+	for _, m := range r.Named(name) {
+		if realm == "" || m.Realm == realm {
+			found = append(found, m)
+		}
+	}
+	if len(found) != 1 {
+		return Member{}, false
+	}
+	return found[0], true
+}
+
+// Has reports whether the character is in the roster, the realm has to match too.
+func (r Roster) Has(character Member) bool {
+	_, ok := r.Find(character.Name, character.Realm)
+	return ok
+}
+
+// Raiders returns the members at the highest level in the roster, the ones who can attend current content.
+func (r Roster) Raiders() []Member {
+	maxLevel := 0
+	for _, m := range r.Members {
+		maxLevel = max(maxLevel, m.Level)
+	}
+	var raiders []Member
+	for _, m := range r.Members {
+		if m.Level == maxLevel {
+			raiders = append(raiders, m)
+		}
+	}
+	return raiders
+}
+
+type rosterResp struct {
+	Members []struct {
+		Character struct {
+			Name  string `json:"name"`
+			Level int    `json:"level"`
+			Realm struct {
+				Slug string `json:"slug"`
+			} `json:"realm"`
+		} `json:"character"`
+		Rank int `json:"rank"`
+	} `json:"members"`
+}
+
+type accountResp struct {
+	WowAccounts []struct {
+		Characters []struct {
+			Name  string `json:"name"`
+			Level int    `json:"level"`
+			Realm struct {
+				Slug string `json:"slug"`
+			} `json:"realm"`
+		} `json:"characters"`
+	} `json:"wow_accounts"`
+}
+
+type characterResp struct {
+	Guild *struct {
+		Key struct {
+			Href string `json:"href"`
+		} `json:"key"`
+	} `json:"guild"`
+}
+
+type tokenResp struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type Client struct {
+	clientID     string
+	clientSecret string
+
+	resty  *resty.Client
+	cache  *ttlcache.Cache[string, Roster]
+	guilds *ttlcache.Cache[string, string]
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewClient(clientId, clientSecret string) *Client {
+	cache := ttlcache.New[string, Roster](
+		ttlcache.WithTTL[string, Roster](time.Hour),
+	)
+	go cache.Start()
+	guilds := ttlcache.New[string, string](
+		ttlcache.WithTTL[string, string](24 * time.Hour),
+	)
+	go guilds.Start()
+	return &Client{
+		clientID:     clientId,
+		clientSecret: clientSecret,
+		resty:        resty.New().SetTimeout(10 * time.Second),
+		cache:        cache,
+		guilds:       guilds,
+	}
+}
+
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Add(time.Minute).Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	var tr tokenResp
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetBasicAuth(c.clientID, c.clientSecret).
+		SetFormData(map[string]string{"grant_type": "client_credentials"}).
+		SetResult(&tr).
+		Post(tokenURL)
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("battle.net oauth token failed: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	c.token = tr.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// GuildHref returns the API link of the guild of the character, the links are cached for a day.
+// Guild slugs drop and fold characters of the name in ways that can't be derived reliably, so guilds are found through a member.
+func (c *Client) GuildHref(ctx context.Context, region, realm, character string) (string, error) {
+	region = strings.ToLower(region)
+	key := region + "/" + realm + "/" + strings.ToLower(character)
+	if item := c.guilds.Get(key); item != nil {
+		return item.Value(), nil
+	}
+
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	var out characterResp
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		SetQueryParams(map[string]string{
+			"namespace": "profile-" + region,
+			"locale":    "en_US",
+		}).
+		SetResult(&out).
+		Get(fmt.Sprintf(characterURL, region, realm, url.PathEscape(strings.ToLower(character))))
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("battle.net character profile: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	if out.Guild == nil {
+		return "", fmt.Errorf("character %v-%v is not in a guild", character, realm)
+	}
+	c.guilds.Set(key, out.Guild.Key.Href, ttlcache.DefaultTTL)
+	return out.Guild.Key.Href, nil
+}
+
+// Roster returns the in-game roster of the guild with the API link from GuildHref, rosters are cached for an hour.
+func (c *Client) Roster(ctx context.Context, guildHref string) (Roster, error) {
+	if item := c.cache.Get(guildHref); item != nil {
+		return item.Value(), nil
+	}
+	u, err := url.Parse(guildHref)
+	if err != nil {
+		return Roster{}, err
+	}
+	// the link comes from the API response, the token must not be sent anywhere else
+	if u.Scheme != "https" || !strings.HasSuffix(u.Host, ".api.blizzard.com") {
+		return Roster{}, fmt.Errorf("unexpected guild link %v", guildHref)
+	}
+	u.Path += "/roster"
+
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return Roster{}, err
+	}
+	var out rosterResp
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		SetQueryParam("locale", "en_US").
+		SetResult(&out).
+		Get(u.String())
+	if err != nil {
+		return Roster{}, err
+	}
+	if resp.IsError() {
+		return Roster{}, fmt.Errorf("battle.net guild roster: %s: %s", resp.Status(), string(resp.Body()))
+	}
+
+	roster := Roster{Members: make([]Member, 0, len(out.Members))}
+	for _, m := range out.Members {
+		roster.Members = append(roster.Members, Member{
+			Name:  m.Character.Name,
+			Realm: m.Character.Realm.Slug,
+			Level: m.Character.Level,
+			Rank:  m.Rank,
+		})
+	}
+	c.cache.Set(guildHref, roster, ttlcache.DefaultTTL)
+	return roster, nil
+}
+
+// AuthorizeURL is the Battle.net login page that sends the user back to redirectURI with a code for AccountCharacters.
+func (c *Client) AuthorizeURL(redirectURI, state string) string {
+	return authorizeURL + "?" + url.Values{
+		"client_id":     {c.clientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {"wow.profile"},
+		"state":         {state},
+	}.Encode()
+}
+
+// AccountCharacters exchanges the login code for a user token and returns the characters of the user's account in the region.
+func (c *Client) AccountCharacters(ctx context.Context, region, code, redirectURI string) ([]Member, error) {
+	var tr tokenResp
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetBasicAuth(c.clientID, c.clientSecret).
+		SetFormData(map[string]string{
+			"grant_type":   "authorization_code",
+			"code":         code,
+			"redirect_uri": redirectURI,
+		}).
+		SetResult(&tr).
+		Post(tokenURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("battle.net oauth code exchange failed: %s: %s", resp.Status(), string(resp.Body()))
+	}
+
+	region = strings.ToLower(region)
+	var out accountResp
+	resp, err = c.resty.R().
+		SetContext(ctx).
+		SetAuthToken(tr.AccessToken).
+		SetQueryParams(map[string]string{
+			"namespace": "profile-" + region,
+			"locale":    "en_US",
+		}).
+		SetResult(&out).
+		Get(fmt.Sprintf(accountURL, region))
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("battle.net account profile: %s: %s", resp.Status(), string(resp.Body()))
+	}
+
+	var characters []Member
+	for _, account := range out.WowAccounts {
+		for _, ch := range account.Characters {
+			characters = append(characters, Member{Name: ch.Name, Realm: ch.Realm.Slug, Level: ch.Level})
+		}
+	}
+	return characters, nil
+}
@@ -0,0 +1,111 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+
+	"bot/publicnet"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// horizon is how far ahead events are expanded.
+const horizon = 14 * 24 * time.Hour
+
+// maxFeedSize is the largest feed read, guild calendars are a few kilobytes.
+const maxFeedSize = 2 << 20
+
+// failureTTL is how long a feed that failed to load is not fetched again, every watcher asks on each poll.
+const failureTTL = 2 * time.Minute
+
+type Client struct {
+	resty *resty.Client
+	cache *ttlcache.Cache[string, feed]
+}
+
+// feed is the result of fetching a feed, failures are cached as well.
+type feed struct {
+	events []Event
+	err    error
+}
+
+// NewClient returns a client for feeds set by server admins, it only connects to public addresses.
+func NewClient() *Client {
+	cache := ttlcache.New[string, feed](
+		ttlcache.WithTTL[string, feed](15 * time.Minute),
+	)
+	go cache.Start()
+	return &Client{
+		resty: resty.NewWithClient(publicnet.NewClient(10 * time.Second)),
+		cache: cache,
+	}
+}
+
+// ValidateURL checks a feed url set by a server admin, webcal links are read over https
+// and the host has to resolve to public addresses only.
+func ValidateURL(ctx context.Context, raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "webcal" {
+		u.Scheme = "https"
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return nil, errors.New("url must be http or https with a host")
+	}
+	if err := publicnet.CheckHost(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Events returns the events of the feed from three hours ago until two weeks ahead, ordered by start.
+// Feeds are cached for 15 minutes, failures for failureTTL.
+func (c *Client) Events(ctx context.Context, url string) ([]Event, error) {
+	if item := c.cache.Get(url); item != nil {
+		return item.Value().events, item.Value().err
+	}
+	events, err := c.fetch(ctx, url)
+	switch {
+	case err == nil:
+		c.cache.Set(url, feed{events: events}, ttlcache.DefaultTTL)
+	case !errors.Is(err, context.Canceled):
+		// a canceled fetch says nothing about the feed, a timed out one does
+		c.cache.Set(url, feed{err: err}, failureTTL)
+	}
+	return events, err
+}
+
+func (c *Client) fetch(ctx context.Context, url string) ([]Event, error) {
+	resp, err := c.resty.R().SetContext(ctx).SetDoNotParseResponse(true).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.RawBody()
+	defer body.Close()
+	if resp.IsError() {
+		return nil, fmt.Errorf("calendar feed: %s", resp.Status())
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxFeedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFeedSize {
+		return nil, fmt.Errorf("calendar feed is larger than %v bytes", maxFeedSize)
+	}
+	now := time.Now()
+	events, err := Parse(bytes.NewReader(data), now.Add(-defaultDuration), now.Add(horizon))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
@@ -0,0 +1,230 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	// feeds name their time zones, minimal images come without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// defaultDuration is used for events without an end, raid-helper exports only the start of a raid.
+const defaultDuration = 3 * time.Hour
+
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// rule is the subset of RRULE guild calendars use: daily or weekly repeats with BYDAY, INTERVAL, COUNT and UNTIL.
+type rule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+type vevent struct {
+	Event
+	rule *rule
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse reads the events of an iCalendar feed and expands repeating ones into occurrences between from and to.
+func Parse(r io.Reader, from, to time.Time) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events  []Event
+		current *vevent
+		found   bool
+	)
+	for _, line := range lines {
+		name, params, value := splitLine(line)
+		switch {
+		case name == "BEGIN" && value == "VCALENDAR":
+			found = true
+		case name == "BEGIN" && value == "VEVENT":
+			current = &vevent{}
+		case name == "END" && value == "VEVENT" && current != nil:
+			if !current.Start.IsZero() {
+				if current.End.IsZero() {
+					current.End = current.Start.Add(defaultDuration)
+				}
+				events = append(events, current.occurrences(from, to)...)
+			}
+			current = nil
+		case current == nil:
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescape(value)
+		case name == "DTSTART":
+			current.Start, _ = parseTime(params, value)
+		case name == "DTEND":
+			current.End, _ = parseTime(params, value)
+		case name == "RRULE":
+			current.rule = parseRule(value)
+		}
+	}
+	if !found {
+		return nil, errors.New("not an icalendar feed")
+	}
+	return events, nil
+}
+
+// occurrences returns the instances of the event overlapping the range, the uid of a repeat gets its start appended.
+func (e vevent) occurrences(from, to time.Time) []Event {
+	if e.rule == nil {
+		if e.End.After(from) && e.Start.Before(to) {
+			return []Event{e.Event}
+		}
+		return nil
+	}
+
+	var out []Event
+	duration := e.End.Sub(e.Start)
+	emitted := 0
+	add := func(start time.Time) bool {
+		if start.Before(e.Start) {
+			return true
+		}
+		if e.rule.count > 0 && emitted >= e.rule.count {
+			return false
+		}
+		if !e.rule.until.IsZero() && start.After(e.rule.until) {
+			return false
+		}
+		emitted++
+		if start.Add(duration).After(from) && start.Before(to) {
+			out = append(out, Event{
+				UID:     e.UID + "/" + start.UTC().Format("20060102T150405Z"),
+				Summary: e.Summary,
+				Start:   start,
+				End:     start.Add(duration),
+			})
+		}
+		return true
+	}
+
+	step := 7
+	if e.rule.freq == "DAILY" {
+		step = 1
+	}
+	step *= e.rule.interval
+	// weekly repeats are walked week by week from the monday of the first week
+	base := e.Start
+	if e.rule.freq == "WEEKLY" {
+		base = base.AddDate(0, 0, -((int(base.Weekday()) + 6) % 7))
+	}
+	for k := 0; ; k++ {
+		period := base.AddDate(0, 0, k*step)
+		if !period.Before(to) {
+			return out
+		}
+		if e.rule.freq == "WEEKLY" && len(e.rule.byDay) > 0 {
+			for _, day := range e.rule.byDay {
+				if !add(period.AddDate(0, 0, (int(day)+6)%7)) {
+					return out
+				}
+			}
+			continue
+		}
+		if !add(e.Start.AddDate(0, 0, k*step)) {
+			return out
+		}
+	}
+}
+
+func parseRule(value string) *rule {
+	r := &rule{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			r.freq = val
+		case "INTERVAL":
+			if n, err := strconv.Atoi(val); err == nil && n > 0 {
+				r.interval = n
+			}
+		case "COUNT":
+			r.count, _ = strconv.Atoi(val)
+		case "UNTIL":
+			r.until, _ = parseTime(nil, val)
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				if wd, ok := weekdays[d]; ok {
+					r.byDay = append(r.byDay, wd)
+				}
+			}
+		}
+	}
+	if r.freq != "DAILY" && r.freq != "WEEKLY" {
+		return nil
+	}
+	slices.SortFunc(r.byDay, func(a, b time.Weekday) int { return (int(a)+6)%7 - (int(b)+6)%7 })
+	return r
+}
+
+func parseTime(params map[string]string, value string) (time.Time, error) {
+	loc := time.UTC
+	if tz, ok := params["TZID"]; ok {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case len(value) == 8:
+		return time.ParseInLocation("20060102", value, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+// unfold joins continuation lines, they start with a space or a tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// splitLine splits "DTSTART;TZID=Europe/Berlin:20250101T190000" into the name, the parameters and the value.
+func splitLine(line string) (string, map[string]string, string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
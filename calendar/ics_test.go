@@ -0,0 +1,113 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	from := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 14)
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		events  string
+		want    []time.Time
+		summary string
+	}{
+		{
+			name:   "single event",
+			events: "UID:a\nSUMMARY:Raid\nDTSTART:20250305T190000Z\nDTEND:20250305T220000Z\n",
+			want:   []time.Time{at(5, 19)},
+		},
+		{
+			name:   "event outside the range",
+			events: "UID:a\nDTSTART:20250401T190000Z\n",
+		},
+		{
+			name:   "weekly by day",
+			events: "UID:a\nDTSTART:20250305T190000Z\nRRULE:FREQ=WEEKLY;BYDAY=WE,TH\n",
+			want:   []time.Time{at(5, 19), at(6, 19), at(12, 19), at(13, 19)},
+		},
+		{
+			name:   "weekly with count",
+			events: "UID:a\nDTSTART:20250226T190000Z\nRRULE:FREQ=WEEKLY;COUNT=2\n",
+			want:   []time.Time{at(5, 19)},
+		},
+		{
+			name:   "daily with interval and until",
+			events: "UID:a\nDTSTART:20250303T190000Z\nRRULE:FREQ=DAILY;INTERVAL=3;UNTIL=20250310T000000Z\n",
+			want:   []time.Time{at(3, 19), at(6, 19), at(9, 19)},
+		},
+		{
+			name:   "unsupported frequency is a single event",
+			events: "UID:a\nDTSTART:20250304T190000Z\nRRULE:FREQ=MONTHLY\n",
+			want:   []time.Time{at(4, 19)},
+		},
+		{
+			name:   "event without start",
+			events: "UID:a\nSUMMARY:Raid\n",
+		},
+		{
+			name:    "folded and escaped summary",
+			events:  "UID:a\nSUMMARY:Mythic\\, prog\n  night\nDTSTART:20250305T190000Z\n",
+			want:    []time.Time{at(5, 19)},
+			summary: "Mythic, prog night",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\n" + tt.events + "END:VEVENT\nEND:VCALENDAR\n"
+			events, err := Parse(strings.NewReader(feed), from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %v events, want %v: %v", len(events), len(tt.want), events)
+			}
+			for i, e := range events {
+				if !e.Start.Equal(tt.want[i]) {
+					t.Errorf("event %v starts at %v, want %v", i, e.Start, tt.want[i])
+				}
+				if e.End.Sub(e.Start) != defaultDuration {
+					t.Errorf("event %v lasts %v, want %v", i, e.End.Sub(e.Start), defaultDuration)
+				}
+				if tt.summary != "" && e.Summary != tt.summary {
+					t.Errorf("summary %q, want %q", e.Summary, tt.summary)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	feed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:a\nDTSTART;TZID=Europe/Berlin:20250305T190000\nEND:VEVENT\nEND:VCALENDAR\n"
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	events, err := Parse(strings.NewReader(feed), from, from.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 3, 5, 18, 0, 0, 0, time.UTC); len(events) != 1 || !events[0].Start.Equal(want) {
+		t.Fatalf("got %v, want one event at %v", events, want)
+	}
+}
+
+func TestParseRepeatUID(t *testing.T) {
+	feed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:raid\nDTSTART:20250305T190000Z\nRRULE:FREQ=DAILY;COUNT=2\nEND:VEVENT\nEND:VCALENDAR\n"
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	events, err := Parse(strings.NewReader(feed), from, from.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].UID != "raid/20250305T190000Z" || events[1].UID != "raid/20250306T190000Z" {
+		t.Fatalf("unexpected uids: %v", events)
+	}
+}
+
+func TestParseNotACalendar(t *testing.T) {
+	if _, err := Parse(strings.NewReader("<html></html>"), time.Now(), time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
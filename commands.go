@@ -1,46 +1,74 @@
 package main
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"slices"
+	"sync/atomic"
+
+	"bot/features"
+	"bot/i18n"
+	"bot/storage"
+	"bot/warcraftlogs"
+
+	"github.com/bwmarrin/discordgo"
+)
 
 var (
-	idMinValue               = 1.0
-	idMaxValue               = 9007199254740991.0
-	wipeCutoffMinValue       = 1.0
-	wipeCutoffMaxValue       = 50.0
-	adminPerms         int64 = discordgo.PermissionAdministrator
-	commands                 = []*discordgo.ApplicationCommand{
+	idMinValue                 = 1.0
+	idMaxValue                 = 9007199254740991.0
+	wipeCutoffMinValue         = 1.0
+	wipeCutoffMaxValue         = 50.0
+	pollIntervalMinValue       = 1.0
+	pollIntervalMaxValue       = 10.0
+	adminPerms           int64 = discordgo.PermissionAdministrator
+	commands                   = []*discordgo.ApplicationCommand{
 		{
 			Name:        "set-config",
 			Description: "Set bot configuration",
 			DescriptionLocalizations: &map[discordgo.Locale]string{
-				discordgo.Russian: "Настройка бота",
+				discordgo.Russian:   "Настройка бота",
+				discordgo.German:    "Bot konfigurieren",
+				discordgo.French:    "Configurer le bot",
+				discordgo.SpanishES: "Configurar el bot",
 			},
 			Options: []*discordgo.ApplicationCommandOption{
 				{
 					Type: discordgo.ApplicationCommandOptionChannel,
 					Name: "channel",
 					NameLocalizations: map[discordgo.Locale]string{
-						discordgo.Russian: "канал",
+						discordgo.Russian:   "канал",
+						discordgo.German:    "kanal",
+						discordgo.French:    "salon",
+						discordgo.SpanishES: "canal",
 					},
-					Description: "Text channel for notifications",
+					Description: "Text or forum channel for notifications",
 					DescriptionLocalizations: map[discordgo.Locale]string{
-						discordgo.Russian: "Текстовый канал для уведомлений",
+						discordgo.Russian:   "Текстовый канал или форум для уведомлений",
+						discordgo.German:    "Text- oder Forenkanal für Benachrichtigungen",
+						discordgo.French:    "Salon textuel ou forum pour les notifications",
+						discordgo.SpanishES: "Canal de texto o foro para las notificaciones",
 					},
 					Required: true,
 					ChannelTypes: []discordgo.ChannelType{
 						discordgo.ChannelTypeGuildText,
 						discordgo.ChannelTypeGuildNews,
+						discordgo.ChannelTypeGuildForum,
 					},
 				},
 				{
 					Type: discordgo.ApplicationCommandOptionInteger,
 					Name: "guild_id",
 					NameLocalizations: map[discordgo.Locale]string{
-						discordgo.Russian: "идентификатор_гильдии",
+						discordgo.Russian:   "идентификатор_гильдии",
+						discordgo.German:    "gilden_id",
+						discordgo.French:    "id_guilde",
+						discordgo.SpanishES: "id_hermandad",
 					},
-					Description: "Guild id from warcraftlogs.com",
+					Description: "Guild id from the log site, warcraftlogs.com by default",
 					DescriptionLocalizations: map[discordgo.Locale]string{
-						discordgo.Russian: "Идентификатор гильдии на warcraftlogs.com",
+						discordgo.Russian:   "Идентификатор гильдии на сайте логов, по умолчанию warcraftlogs.com",
+						discordgo.German:    "Gilden-ID auf der Log-Seite, standardmäßig warcraftlogs.com",
+						discordgo.French:    "Identifiant de la guilde sur le site de logs, warcraftlogs.com par défaut",
+						discordgo.SpanishES: "ID de la hermandad en el sitio de logs, warcraftlogs.com por defecto",
 					},
 					Required: true,
 					MinValue: &idMinValue,
@@ -50,16 +78,78 @@ var (
 					Type: discordgo.ApplicationCommandOptionInteger,
 					Name: "wipe_cutoff",
 					NameLocalizations: map[discordgo.Locale]string{
-						discordgo.Russian: "wipe_cutoff",
+						discordgo.Russian:   "wipe_cutoff",
+						discordgo.German:    "wipe_cutoff",
+						discordgo.French:    "wipe_cutoff",
+						discordgo.SpanishES: "wipe_cutoff",
 					},
 					Description: "The number of deaths after which all subsequent events should be ignored",
 					DescriptionLocalizations: map[discordgo.Locale]string{
-						discordgo.Russian: "Количество смертей, после которого все последующие события игнорируются",
+						discordgo.Russian:   "Количество смертей, после которого все последующие события игнорируются",
+						discordgo.German:    "Anzahl der Tode, nach der alle weiteren Ereignisse ignoriert werden",
+						discordgo.French:    "Nombre de morts après lequel les événements suivants sont ignorés",
+						discordgo.SpanishES: "Número de muertes tras el cual se ignoran los eventos siguientes",
 					},
 					Required: true,
 					MinValue: &wipeCutoffMinValue,
 					MaxValue: wipeCutoffMaxValue,
 				},
+				{
+					Type: discordgo.ApplicationCommandOptionInteger,
+					Name: "poll_interval",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "интервал_обновления",
+						discordgo.German:    "abfrageintervall",
+						discordgo.French:    "intervalle",
+						discordgo.SpanishES: "intervalo",
+					},
+					Description: "Minutes between report checks, the bot default when omitted",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Минут между проверками логов, по умолчанию настройка бота",
+						discordgo.German:    "Minuten zwischen Log-Abfragen, ohne Angabe der Standard des Bots",
+						discordgo.French:    "Minutes entre les vérifications des logs, valeur du bot par défaut",
+						discordgo.SpanishES: "Minutos entre comprobaciones de los logs, por defecto el valor del bot",
+					},
+					Required: false,
+					MinValue: &pollIntervalMinValue,
+					MaxValue: pollIntervalMaxValue,
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "language",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "язык",
+						discordgo.German:    "sprache",
+						discordgo.French:    "langue",
+						discordgo.SpanishES: "idioma",
+					},
+					Description: "Language of report messages, the server language when omitted",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Язык сообщений с логами, по умолчанию язык сервера",
+						discordgo.German:    "Sprache der Log-Nachrichten, ohne Angabe die Sprache des Servers",
+						discordgo.French:    "Langue des messages de logs, celle du serveur par défaut",
+						discordgo.SpanishES: "Idioma de los mensajes de logs, por defecto el del servidor",
+					},
+					Choices: languageChoices(),
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "site",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "сайт",
+						discordgo.German:    "seite",
+						discordgo.French:    "site",
+						discordgo.SpanishES: "sitio",
+					},
+					Description: "Log site of the guild, kept when omitted",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Сайт логов гильдии, без значения не меняется",
+						discordgo.German:    "Log-Seite der Gilde, ohne Angabe unverändert",
+						discordgo.French:    "Site de logs de la guilde, inchangé par défaut",
+						discordgo.SpanishES: "Sitio de logs de la hermandad, sin valor no cambia",
+					},
+					Choices: siteChoices(),
+				},
 			},
 			DefaultMemberPermissions: &adminPerms,
 			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
@@ -68,19 +158,1106 @@ var (
 			Name:        "get-config",
 			Description: "Show current configuration",
 			DescriptionLocalizations: &map[discordgo.Locale]string{
-				discordgo.Russian: "Посмотреть текущие настройки",
+				discordgo.Russian:   "Посмотреть текущие настройки",
+				discordgo.German:    "Aktuelle Konfiguration anzeigen",
+				discordgo.French:    "Afficher la configuration actuelle",
+				discordgo.SpanishES: "Mostrar la configuración actual",
 			},
 			Options:                  []*discordgo.ApplicationCommandOption{},
 			DefaultMemberPermissions: &adminPerms,
 			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
 		},
+		{
+			Name:        "export-config",
+			Description: "Export the bot configuration of this server as a file",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Выгрузить настройки бота на этом сервере в файл",
+				discordgo.German:    "Die Bot-Konfiguration dieses Servers als Datei exportieren",
+				discordgo.French:    "Exporter la configuration du bot de ce serveur dans un fichier",
+				discordgo.SpanishES: "Exportar la configuración del bot de este servidor como archivo",
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "import-config",
+			Description: "Import a bot configuration exported on this or another server",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Загрузить настройки бота, выгруженные на этом или другом сервере",
+				discordgo.German:    "Eine auf diesem oder einem anderen Server exportierte Bot-Konfiguration importieren",
+				discordgo.French:    "Importer une configuration du bot exportée sur ce serveur ou un autre",
+				discordgo.SpanishES: "Importar una configuración del bot exportada en este u otro servidor",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionAttachment,
+					Name: "file",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "файл",
+						discordgo.German:    "datei",
+						discordgo.French:    "fichier",
+						discordgo.SpanishES: "archivo",
+					},
+					Description: "File created by /export-config",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Файл, созданный /export-config",
+						discordgo.German:    "Mit /export-config erstellte Datei",
+						discordgo.French:    "Fichier créé par /export-config",
+						discordgo.SpanishES: "Archivo creado con /export-config",
+					},
+					Required: true,
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "embed-settings",
+			Description: "Configure how report messages look",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Настройка вида сообщений с логами",
+				discordgo.German:    "Aussehen der Log-Nachrichten einstellen",
+				discordgo.French:    "Configurer l'apparence des messages de logs",
+				discordgo.SpanishES: "Configurar el aspecto de los mensajes de logs",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "mode",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "режим",
+						discordgo.German:    "modus",
+						discordgo.French:    "mode",
+						discordgo.SpanishES: "modo",
+					},
+					Description: "Compact snapshot or detailed tables",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Краткая сводка или подробные таблицы",
+						discordgo.German:    "Kurze Übersicht oder ausführliche Tabellen",
+						discordgo.French:    "Résumé court ou tableaux détaillés",
+						discordgo.SpanishES: "Resumen breve o tablas detalladas",
+					},
+					Required: false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{
+							Name: "Compact",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Краткий",
+								discordgo.German:    "Kompakt",
+								discordgo.French:    "Compact",
+								discordgo.SpanishES: "Compacto",
+							},
+							Value: storage.EmbedModeCompact,
+						},
+						{
+							Name: "Detailed",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Подробный",
+								discordgo.German:    "Ausführlich",
+								discordgo.French:    "Détaillé",
+								discordgo.SpanishES: "Detallado",
+							},
+							Value: storage.EmbedModeDetailed,
+						},
+					},
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "theme",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "тема",
+						discordgo.German:    "thema",
+						discordgo.French:    "theme",
+						discordgo.SpanishES: "tema",
+					},
+					Description: "Embed colors: live/offline only or by raid difficulty",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Цвета сообщений: только онлайн/офлайн или по сложности рейда",
+						discordgo.German:    "Farben: nur live/offline oder nach Schlachtzugsschwierigkeit",
+						discordgo.French:    "Couleurs : en direct/hors ligne ou selon la difficulté du raid",
+						discordgo.SpanishES: "Colores: solo en directo/desconectado o por dificultad de banda",
+					},
+					Required: false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{
+							Name: "Classic",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Классическая",
+								discordgo.German:    "Klassisch",
+								discordgo.French:    "Classique",
+								discordgo.SpanishES: "Clásico",
+							},
+							Value: storage.ThemeClassic,
+						},
+						{
+							Name: "Difficulty",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "По сложности",
+								discordgo.German:    "Nach Schwierigkeit",
+								discordgo.French:    "Par difficulté",
+								discordgo.SpanishES: "Por dificultad",
+							},
+							Value: storage.ThemeDifficulty,
+						},
+					},
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "layout",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "макет",
+						discordgo.German:    "layout",
+						discordgo.French:    "disposition",
+						discordgo.SpanishES: "diseño",
+					},
+					Description: "Classic embed or the newer components layout",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Классический embed или новый макет из компонентов",
+						discordgo.German:    "Klassisches Embed oder das neuere Komponenten-Layout",
+						discordgo.French:    "Embed classique ou la nouvelle disposition en composants",
+						discordgo.SpanishES: "Embed clásico o el nuevo diseño de componentes",
+					},
+					Required: false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{
+							Name: "Embed",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Embed",
+								discordgo.German:    "Embed",
+								discordgo.French:    "Embed",
+								discordgo.SpanishES: "Embed",
+							},
+							Value: storage.LayoutEmbed,
+						},
+						{
+							Name: "Components",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Компоненты",
+								discordgo.German:    "Komponenten",
+								discordgo.French:    "Composants",
+								discordgo.SpanishES: "Componentes",
+							},
+							Value: storage.LayoutComponents,
+						},
+					},
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "medals",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "медали",
+						discordgo.German:    "medaillen",
+						discordgo.French:    "medailles",
+						discordgo.SpanishES: "medallas",
+					},
+					Description: "Three space separated emoji for the top places, or default",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Три эмодзи через пробел для первых мест или default",
+						discordgo.German:    "Drei durch Leerzeichen getrennte Emojis für die ersten Plätze oder default",
+						discordgo.French:    "Trois emojis séparés par des espaces pour les premières places, ou default",
+						discordgo.SpanishES: "Tres emojis separados por espacios para los primeros puestos, o default",
+					},
+					Required: false,
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "mentions",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "упоминания",
+						discordgo.German:    "erwaehnungen",
+						discordgo.French:    "mentions",
+						discordgo.SpanishES: "menciones",
+					},
+					Description: "Mention users next to the characters they claimed",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Упоминать пользователей рядом с их персонажами",
+						discordgo.German:    "Benutzer neben ihren beanspruchten Charakteren erwähnen",
+						discordgo.French:    "Mentionner les utilisateurs à côté de leurs personnages",
+						discordgo.SpanishES: "Mencionar a los usuarios junto a sus personajes",
+					},
+					Required: false,
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "spoilers",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "спойлеры",
+						discordgo.German:    "spoiler",
+						discordgo.French:    "spoilers",
+						discordgo.SpanishES: "spoilers",
+					},
+					Description: "Hide boss names and kills for streamed progression",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Скрывать названия боссов и убийства во время стримов прогресса",
+						discordgo.German:    "Bossnamen und Kills bei gestreamtem Progress verbergen",
+						discordgo.French:    "Masquer les noms de boss et les kills pendant les streams de progression",
+						discordgo.SpanishES: "Ocultar nombres de jefes y muertes durante streams de progreso",
+					},
+					Required: false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{
+							Name: "Off",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Выключено",
+								discordgo.German:    "Aus",
+								discordgo.French:    "Désactivé",
+								discordgo.SpanishES: "Desactivado",
+							},
+							Value: "off",
+						},
+						{
+							Name: "Spoiler tags",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Под спойлером",
+								discordgo.German:    "Spoiler-Tags",
+								discordgo.French:    "Balises spoiler",
+								discordgo.SpanishES: "Etiquetas de spoiler",
+							},
+							Value: storage.SpoilersTags,
+						},
+						{
+							Name: "Generic labels",
+							NameLocalizations: map[discordgo.Locale]string{
+								discordgo.Russian:   "Без названий",
+								discordgo.German:    "Neutrale Bezeichnungen",
+								discordgo.French:    "Libellés génériques",
+								discordgo.SpanishES: "Etiquetas genéricas",
+							},
+							Value: storage.SpoilersGeneric,
+						},
+					},
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "webhook",
+			Description: "Post updates through a channel webhook with a custom name and avatar",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Публиковать обновления через вебхук канала со своим именем и аватаром",
+				discordgo.German:    "Updates über einen Kanal-Webhook mit eigenem Namen und Avatar posten",
+				discordgo.French:    "Publier via un webhook du salon avec un nom et un avatar personnalisés",
+				discordgo.SpanishES: "Publicar mediante un webhook del canal con nombre y avatar propios",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "enabled",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "включен",
+						discordgo.German:    "aktiviert",
+						discordgo.French:    "active",
+						discordgo.SpanishES: "activado",
+					},
+					Description: "Use a webhook instead of bot messages",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Использовать вебхук вместо сообщений бота",
+						discordgo.German:    "Webhook statt Bot-Nachrichten verwenden",
+						discordgo.French:    "Utiliser un webhook au lieu des messages du bot",
+						discordgo.SpanishES: "Usar un webhook en lugar de mensajes del bot",
+					},
+					Required: true,
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "name",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "имя",
+						discordgo.German:    "name",
+						discordgo.French:    "nom",
+						discordgo.SpanishES: "nombre",
+					},
+					Description: "Name shown on the posts, e.g. the raid team",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Имя автора сообщений, например название рейдового состава",
+						discordgo.German:    "Angezeigter Name der Beiträge, z. B. das Raidteam",
+						discordgo.French:    "Nom affiché sur les messages, par exemple l'équipe de raid",
+						discordgo.SpanishES: "Nombre mostrado en las publicaciones, p. ej. el equipo de banda",
+					},
+					MaxLength: 80,
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "avatar_url",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "аватар",
+						discordgo.German:    "avatar",
+						discordgo.French:    "avatar",
+						discordgo.SpanishES: "avatar",
+					},
+					Description: "Image URL of the avatar shown on the posts",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Ссылка на изображение аватара",
+						discordgo.German:    "Bild-URL des angezeigten Avatars",
+						discordgo.French:    "URL de l'image de l'avatar affiché",
+						discordgo.SpanishES: "URL de la imagen del avatar mostrado",
+					},
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "events",
+			Description: "Send report events as signed JSON to your own endpoint, disabled when the url is omitted",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Отправлять события логов в виде подписанного JSON, без ссылки отключает",
+				discordgo.German:    "Log-Ereignisse als signiertes JSON senden, ohne URL deaktiviert",
+				discordgo.French:    "Envoyer les événements des logs en JSON signé, désactivé sans URL",
+				discordgo.SpanishES: "Enviar eventos de los logs como JSON firmado, sin URL se desactiva",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "url",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "ссылка",
+						discordgo.German:    "url",
+						discordgo.French:    "url",
+						discordgo.SpanishES: "url",
+					},
+					Description: "Endpoint receiving POST requests",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Адрес, принимающий POST запросы",
+						discordgo.German:    "Endpunkt, der POST-Anfragen empfängt",
+						discordgo.French:    "Point de terminaison recevant les requêtes POST",
+						discordgo.SpanishES: "Endpoint que recibe peticiones POST",
+					},
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "stats-api",
+			Description: "Serve the latest raid stats as JSON for guild websites, enabling again replaces the token",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Отдавать последнюю статистику рейда в JSON для сайтов гильдий, повторное включение меняет токен",
+				discordgo.German:    "Die neuesten Raid-Statistiken als JSON für Gilden-Websites bereitstellen, erneutes Aktivieren ersetzt das Token",
+				discordgo.French:    "Servir les dernières statistiques de raid en JSON pour les sites de guilde, réactiver remplace le jeton",
+				discordgo.SpanishES: "Servir las últimas estadísticas de raid en JSON para webs de hermandad, activarlo de nuevo reemplaza el token",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "enabled",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "включено",
+						discordgo.German:    "aktiviert",
+						discordgo.French:    "activé",
+						discordgo.SpanishES: "activado",
+					},
+					Description: "Whether the stats api answers for this server",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Отвечает ли API статистики для этого сервера",
+						discordgo.German:    "Ob die Statistik-API für diesen Server antwortet",
+						discordgo.French:    "Si l'API de statistiques répond pour ce serveur",
+						discordgo.SpanishES: "Si la API de estadísticas responde para este servidor",
+					},
+					Required: true,
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "time-series",
+			Description: "Record deaths, pulls and boss percentages of every update for Grafana",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Записывать смерти, пуллы и проценты боссов при каждом обновлении для Grafana",
+				discordgo.German:    "Tode, Pulls und Boss-Prozente jeder Aktualisierung für Grafana aufzeichnen",
+				discordgo.French:    "Enregistrer les morts, pulls et pourcentages des boss à chaque mise à jour pour Grafana",
+				discordgo.SpanishES: "Registrar muertes, pulls y porcentajes de jefes en cada actualización para Grafana",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "enabled",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "включено",
+						discordgo.German:    "aktiviert",
+						discordgo.French:    "activé",
+						discordgo.SpanishES: "activado",
+					},
+					Description: "Whether updates are recorded, disabling deletes the recorded series",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Записывать ли обновления, отключение удаляет записанные данные",
+						discordgo.German:    "Ob Aktualisierungen aufgezeichnet werden, Deaktivieren löscht die Aufzeichnung",
+						discordgo.French:    "Si les mises à jour sont enregistrées, désactiver supprime les séries enregistrées",
+						discordgo.SpanishES: "Si se registran las actualizaciones, desactivarlo borra las series registradas",
+					},
+					Required: true,
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "sheet",
+			Description: "Append the stats of every raid night to a Google Sheet, disabled when the spreadsheet is omitted",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Добавлять статистику каждого рейда в Google таблицу, без таблицы отключает",
+				discordgo.German:    "Statistiken jedes Raidabends an ein Google Sheet anhängen, ohne Tabelle deaktiviert",
+				discordgo.French:    "Ajouter les statistiques de chaque soirée de raid à un Google Sheet, désactivé sans feuille",
+				discordgo.SpanishES: "Añadir las estadísticas de cada noche de raid a una hoja de Google, sin hoja se desactiva",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "spreadsheet",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "таблица",
+						discordgo.German:    "tabelle",
+						discordgo.French:    "feuille",
+						discordgo.SpanishES: "hoja",
+					},
+					Description: "Spreadsheet url or id",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Ссылка или id таблицы",
+						discordgo.German:    "URL oder ID der Tabelle",
+						discordgo.French:    "URL ou id de la feuille de calcul",
+						discordgo.SpanishES: "URL o id de la hoja de cálculo",
+					},
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "tab",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "лист",
+						discordgo.German:    "blatt",
+						discordgo.French:    "onglet",
+						discordgo.SpanishES: "pestaña",
+					},
+					Description: "Tab to append to, the first one by default",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Лист для записи, по умолчанию первый",
+						discordgo.German:    "Blatt zum Anhängen, standardmäßig das erste",
+						discordgo.French:    "Onglet où ajouter les lignes, le premier par défaut",
+						discordgo.SpanishES: "Pestaña donde añadir, la primera por defecto",
+					},
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "calendar",
+			Description: "Poll often only around raids from an ICS calendar, disabled when the url is omitted",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Часто проверять логи только во время рейдов из ICS календаря, без ссылки отключает",
+				discordgo.German:    "Nur rund um Raids aus einem ICS-Kalender häufig abfragen, ohne URL deaktiviert",
+				discordgo.French:    "Interroger souvent uniquement autour des raids d'un calendrier ICS, désactivé sans URL",
+				discordgo.SpanishES: "Consultar a menudo solo durante las raids de un calendario ICS, sin URL se desactiva",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "url",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "ссылка",
+						discordgo.German:    "url",
+						discordgo.French:    "url",
+						discordgo.SpanishES: "url",
+					},
+					Description: "ICS feed url, e.g. the raid-helper calendar export",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Ссылка на ICS, например экспорт календаря raid-helper",
+						discordgo.German:    "ICS-Feed-URL, z. B. der Kalenderexport von raid-helper",
+						discordgo.French:    "URL du flux ICS, par exemple l'export du calendrier raid-helper",
+						discordgo.SpanishES: "URL del feed ICS, por ejemplo la exportación del calendario de raid-helper",
+					},
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "announce",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "анонс",
+						discordgo.German:    "ankündigen",
+						discordgo.French:    "annoncer",
+						discordgo.SpanishES: "anunciar",
+					},
+					Description: "Announce raids 30 minutes before they start, on by default",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Анонсировать рейд за 30 минут до начала, включено по умолчанию",
+						discordgo.German:    "Raids 30 Minuten vor Beginn ankündigen, standardmäßig an",
+						discordgo.French:    "Annoncer les raids 30 minutes avant leur début, activé par défaut",
+						discordgo.SpanishES: "Anunciar las raids 30 minutos antes de empezar, activado por defecto",
+					},
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "mirror",
+			Description: "Mirror report updates to a chat on another platform, disabled when the target is omitted",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Дублировать обновления логов в чат на другой платформе, без чата отключает",
+				discordgo.German:    "Log-Updates in einen Chat auf einer anderen Plattform spiegeln, ohne Ziel deaktiviert",
+				discordgo.French:    "Reproduire les mises à jour des logs dans un chat d'une autre plateforme, désactivé sans cible",
+				discordgo.SpanishES: "Replicar las actualizaciones de los logs en un chat de otra plataforma, sin destino se desactiva",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "platform",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "платформа",
+						discordgo.German:    "plattform",
+						discordgo.French:    "plateforme",
+						discordgo.SpanishES: "plataforma",
+					},
+					Description: "Chat platform",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Платформа чата",
+						discordgo.German:    "Chat-Plattform",
+						discordgo.French:    "Plateforme de chat",
+						discordgo.SpanishES: "Plataforma de chat",
+					},
+					Required: true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Slack", Value: "slack"},
+						{Name: "Telegram", Value: "telegram"},
+					},
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "target",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "чат",
+						discordgo.German:    "ziel",
+						discordgo.French:    "cible",
+						discordgo.SpanishES: "destino",
+					},
+					Description: "Slack channel id or Telegram chat id, the bot has to be a member of the chat",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "ID канала Slack или чата Telegram, бот должен состоять в чате",
+						discordgo.German:    "Slack-Kanal-ID oder Telegram-Chat-ID, der Bot muss Mitglied des Chats sein",
+						discordgo.French:    "Id du salon Slack ou du chat Telegram, le bot doit en être membre",
+						discordgo.SpanishES: "Id del canal de Slack o del chat de Telegram, el bot debe ser miembro del chat",
+					},
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "twitch",
+			Description: "Link a raider to a Twitch channel shown on live reports, removed when the channel is omitted",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Привязать рейдера к каналу Twitch для живых логов, без канала удаляет привязку",
+				discordgo.German:    "Einen Raider mit einem Twitch-Kanal für Live-Logs verknüpfen, ohne Kanal entfernt",
+				discordgo.French:    "Associer un raider à une chaîne Twitch affichée sur les logs en direct, retiré sans chaîne",
+				discordgo.SpanishES: "Vincular un raider a un canal de Twitch mostrado en los logs en vivo, sin canal se elimina",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "character",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "персонаж",
+						discordgo.German:    "charakter",
+						discordgo.French:    "personnage",
+						discordgo.SpanishES: "personaje",
+					},
+					Description: "Character name",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Имя персонажа",
+						discordgo.German:    "Name des Charakters",
+						discordgo.French:    "Nom du personnage",
+						discordgo.SpanishES: "Nombre del personaje",
+					},
+					Required: true,
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "channel",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "канал",
+						discordgo.German:    "kanal",
+						discordgo.French:    "chaîne",
+						discordgo.SpanishES: "canal",
+					},
+					Description: "Twitch channel name or link",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Имя или ссылка на канал Twitch",
+						discordgo.German:    "Name oder Link des Twitch-Kanals",
+						discordgo.French:    "Nom ou lien de la chaîne Twitch",
+						discordgo.SpanishES: "Nombre o enlace del canal de Twitch",
+					},
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "scheduled-events",
+			Description: "Create Discord events for the raids of the calendar and thread the summary of each raid",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Создавать события Discord для рейдов из календаря и ветку с итогами каждого рейда",
+				discordgo.German:    "Discord-Events für die Raids des Kalenders erstellen und die Zusammenfassung als Thread anhängen",
+				discordgo.French:    "Créer des événements Discord pour les raids du calendrier et un fil avec le résumé de chaque raid",
+				discordgo.SpanishES: "Crear eventos de Discord para las raids del calendario y un hilo con el resumen de cada raid",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "enabled",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "включено",
+						discordgo.German:    "aktiviert",
+						discordgo.French:    "activé",
+						discordgo.SpanishES: "activado",
+					},
+					Description: "Whether raids get Discord events",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Создавать ли события Discord для рейдов",
+						discordgo.German:    "Ob Raids Discord-Events erhalten",
+						discordgo.French:    "Si les raids ont des événements Discord",
+						discordgo.SpanishES: "Si las raids tienen eventos de Discord",
+					},
+					Required: true,
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "raid-threads",
+			Description: "Post each raid night into its own thread",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Публиковать каждый рейд в отдельной ветке",
+				discordgo.German:    "Jeden Raidabend in einem eigenen Thread posten",
+				discordgo.French:    "Publier chaque soirée de raid dans son propre fil",
+				discordgo.SpanishES: "Publicar cada noche de raid en su propio hilo",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "enabled",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "включено",
+						discordgo.German:    "aktiviert",
+						discordgo.French:    "activé",
+						discordgo.SpanishES: "activado",
+					},
+					Description: "Whether raid nights get their own thread",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Создавать ли отдельную ветку для каждого рейда",
+						discordgo.German:    "Ob Raidabende einen eigenen Thread erhalten",
+						discordgo.French:    "Si les soirées de raid ont leur propre fil",
+						discordgo.SpanishES: "Si las noches de raid tienen su propio hilo",
+					},
+					Required: true,
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "backfill",
+			Description: "Import the raid nights of the current tier from the log site for the leaderboard",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Загрузить рейды текущего тира с сайта логов для таблицы лидеров",
+				discordgo.German:    "Die Raidabende des aktuellen Tiers von der Log-Seite für die Bestenliste importieren",
+				discordgo.French:    "Importer les soirées de raid du palier actuel depuis le site de logs pour le classement",
+				discordgo.SpanishES: "Importar las noches de raid del tier actual desde la web de logs para la clasificación",
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "link-replies",
+			Description: "Reply with the stats of log links posted in a channel",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Отвечать статистикой на ссылки на логи, опубликованные в канале",
+				discordgo.German:    "Auf in einem Kanal gepostete Log-Links mit ihren Statistiken antworten",
+				discordgo.French:    "Répondre avec les statistiques des liens de logs publiés dans un salon",
+				discordgo.SpanishES: "Responder con las estadísticas de los enlaces de logs publicados en un canal",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionChannel,
+					Name: "channel",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "канал",
+						discordgo.German:    "kanal",
+						discordgo.French:    "salon",
+						discordgo.SpanishES: "canal",
+					},
+					Description: "Text channel to watch for log links",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Текстовый канал, в котором отслеживаются ссылки на логи",
+						discordgo.German:    "Textkanal, der auf Log-Links überwacht wird",
+						discordgo.French:    "Salon textuel surveillé pour les liens de logs",
+						discordgo.SpanishES: "Canal de texto en el que se vigilan los enlaces de logs",
+					},
+					Required: true,
+					ChannelTypes: []discordgo.ChannelType{
+						discordgo.ChannelTypeGuildText,
+						discordgo.ChannelTypeGuildNews,
+					},
+				},
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "enabled",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "включено",
+						discordgo.German:    "aktiviert",
+						discordgo.French:    "activé",
+						discordgo.SpanishES: "activado",
+					},
+					Description: "Whether log links in the channel get a reply",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Отвечать ли на ссылки на логи в канале",
+						discordgo.German:    "Ob Log-Links im Kanal beantwortet werden",
+						discordgo.French:    "Si les liens de logs du salon reçoivent une réponse",
+						discordgo.SpanishES: "Si los enlaces de logs del canal reciben una respuesta",
+					},
+					Required: true,
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "claim-verification",
+			Description: "Only mention characters whose owners proved them with a Battle.net login",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Упоминать только персонажей, подтверждённых входом через Battle.net",
+				discordgo.German:    "Nur Charaktere erwähnen, deren Besitz per Battle.net-Anmeldung bestätigt wurde",
+				discordgo.French:    "Ne mentionner que les personnages confirmés par une connexion Battle.net",
+				discordgo.SpanishES: "Mencionar solo personajes confirmados con un inicio de sesión en Battle.net",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionBoolean,
+					Name: "required",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "обязательно",
+						discordgo.German:    "erforderlich",
+						discordgo.French:    "obligatoire",
+						discordgo.SpanishES: "obligatorio",
+					},
+					Description: "Whether claims need a Battle.net login",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Требуется ли вход через Battle.net для закрепления персонажей",
+						discordgo.German:    "Ob Ansprüche eine Battle.net-Anmeldung benötigen",
+						discordgo.French:    "Si les revendications nécessitent une connexion Battle.net",
+						discordgo.SpanishES: "Si las reclamaciones necesitan iniciar sesión en Battle.net",
+					},
+					Required: true,
+				},
+			},
+			DefaultMemberPermissions: &adminPerms,
+			Contexts:                 &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "progress",
+			Description: "Show the guild raid progression and ranks from Raider.IO",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Прогресс и рейтинг гильдии на Raider.IO",
+				discordgo.German:    "Schlachtzugsfortschritt und Ränge der Gilde von Raider.IO",
+				discordgo.French:    "Progression et classements de la guilde sur Raider.IO",
+				discordgo.SpanishES: "Progreso y clasificaciones de la hermandad en Raider.IO",
+			},
+			Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "leaderboard",
+			Description: "Show the deaths and parses leaderboard of the current tier",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Таблица лидеров по смертям и парсам за текущий тир",
+				discordgo.German:    "Bestenliste der Tode und Parses des aktuellen Tiers",
+				discordgo.French:    "Classement des morts et des parses du palier actuel",
+				discordgo.SpanishES: "Clasificación de muertes y parses del tier actual",
+			},
+			Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "claim",
+			Description: "Claim your character in the death lists",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Закрепить за собой персонажа",
+				discordgo.German:    "Deinen Charakter in den Todeslisten beanspruchen",
+				discordgo.French:    "Revendiquer votre personnage dans les listes de morts",
+				discordgo.SpanishES: "Reclamar tu personaje en las listas de muertes",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "character",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "персонаж",
+						discordgo.German:    "charakter",
+						discordgo.French:    "personnage",
+						discordgo.SpanishES: "personaje",
+					},
+					Description: "Character name",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Имя персонажа",
+						discordgo.German:    "Name des Charakters",
+						discordgo.French:    "Nom du personnage",
+						discordgo.SpanishES: "Nombre del personaje",
+					},
+					Required: true,
+				},
+			},
+			Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "verify",
+			Description: "Log in with Battle.net to claim your characters in the guild",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Войти через Battle.net, чтобы закрепить своих персонажей в гильдии",
+				discordgo.German:    "Mit Battle.net anmelden, um deine Charaktere in der Gilde zu beanspruchen",
+				discordgo.French:    "Se connecter avec Battle.net pour revendiquer vos personnages de la guilde",
+				discordgo.SpanishES: "Iniciar sesión con Battle.net para reclamar tus personajes de la hermandad",
+			},
+			Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "unclaim",
+			Description: "Remove a character claim",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Открепить персонажа",
+				discordgo.German:    "Anspruch auf einen Charakter entfernen",
+				discordgo.French:    "Retirer la revendication d'un personnage",
+				discordgo.SpanishES: "Quitar la reclamación de un personaje",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "character",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "персонаж",
+						discordgo.German:    "charakter",
+						discordgo.French:    "personnage",
+						discordgo.SpanishES: "personaje",
+					},
+					Description: "Character name",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Имя персонажа",
+						discordgo.German:    "Name des Charakters",
+						discordgo.French:    "Nom du personnage",
+						discordgo.SpanishES: "Nombre del personaje",
+					},
+					Required: true,
+				},
+			},
+			Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
+		{
+			Name:        "feedback",
+			Description: "Send feedback or a bug report to the bot maintainer",
+			DescriptionLocalizations: &map[discordgo.Locale]string{
+				discordgo.Russian:   "Отправить отзыв или сообщить об ошибке разработчику бота",
+				discordgo.German:    "Feedback oder einen Fehlerbericht an den Bot-Betreiber senden",
+				discordgo.French:    "Envoyer un avis ou un rapport de bug au mainteneur du bot",
+				discordgo.SpanishES: "Enviar comentarios o un informe de error al responsable del bot",
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type: discordgo.ApplicationCommandOptionString,
+					Name: "message",
+					NameLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "сообщение",
+						discordgo.German:    "nachricht",
+						discordgo.French:    "message",
+						discordgo.SpanishES: "mensaje",
+					},
+					Description: "What happened or what you would like to see, server settings are attached",
+					DescriptionLocalizations: map[discordgo.Locale]string{
+						discordgo.Russian:   "Что произошло или чего не хватает, настройки сервера будут приложены",
+						discordgo.German:    "Was passiert ist oder was du dir wünschst, die Servereinstellungen werden angehängt",
+						discordgo.French:    "Ce qui s'est passé ou ce que vous aimeriez voir, les réglages du serveur sont joints",
+						discordgo.SpanishES: "Qué ha pasado o qué te gustaría ver, se adjuntan los ajustes del servidor",
+					},
+					Required:  true,
+					MaxLength: 2000,
+				},
+			},
+			Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+		},
 	}
 )
 
-var commandNames = func() []string {
-	names := make([]string, len(commands))
-	for i, cmd := range commands {
+// ownerCommand is registered only in the owner guild and is answered only for the owner.
+var ownerCommand = &discordgo.ApplicationCommand{
+	Name:                     "owner",
+	Description:              "Bot owner tools",
+	DefaultMemberPermissions: &adminPerms,
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "stats",
+			Description: "Servers, watchers and API usage",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "flags",
+			Description: "List feature flags of a server or the global ones",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "server_id",
+					Description: "Discord server id, global flags if omitted",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "flag",
+			Description: "Toggle a feature flag for a server or globally",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "Feature flag",
+					Required:    true,
+					Choices:     flagChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "state",
+					Description: "New state, default removes the override",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "on", Value: "on"},
+						{Name: "off", Value: "off"},
+						{Name: "default", Value: "default"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "server_id",
+					Description: "Discord server id, global if omitted",
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "version",
+			Description: "Build version and commit",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "usage",
+			Description: "Busiest or stalest servers by commands and updates",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "sort",
+					Description: "Order of the list",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "busiest", Value: "busiest"},
+						{Name: "stalest", Value: "stalest"},
+					},
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "broadcast",
+			Description: "Post an announcement to every configured channel",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "message",
+					Description: "Announcement text",
+					Required:    true,
+					MaxLength:   2000,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "unwatch",
+			Description: "Stop the watcher of a server until it is reconfigured or the bot restarts",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "server_id",
+					Description: "Discord server id",
+					Required:    true,
+				},
+			},
+		},
+	},
+	Contexts: &[]discordgo.InteractionContextType{discordgo.InteractionContextGuild},
+}
+
+func flagChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(features.Flags))
+	for i, f := range features.Flags {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: f.Name, Value: f.Name}
+	}
+	return choices
+}
+
+// maxChoices is the discord limit of choices per option.
+const maxChoices = 25
+
+func siteChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(warcraftlogs.Sites))
+	for _, site := range warcraftlogs.Sites {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: site.Name, Value: site.Id})
+	}
+	return choices
+}
+
+func languageChoices() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, locale := range i18n.Locales()[:min(len(i18n.Locales()), maxChoices)] {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  i18n.T(locale, "language.name"),
+			Value: string(locale),
+		})
+	}
+	return choices
+}
+
+// registeredCommands is the current copy of commands with the language choices of the loaded locales. Locale
+// reloads replace it while shards register commands, so it is swapped as a whole and never changed in place.
+var registeredCommands atomic.Pointer[[]*discordgo.ApplicationCommand]
+
+// guildCommands returns the commands to register in the guild.
+func guildCommands(guildId, ownerGuildId string) []*discordgo.ApplicationCommand {
+	cmds := commands
+	if current := registeredCommands.Load(); current != nil {
+		cmds = *current
+	}
+	if ownerGuildId == "" || guildId != ownerGuildId {
+		return cmds
+	}
+	return append(slices.Clone(cmds), ownerCommand)
+}
+
+func commandNames(cmds []*discordgo.ApplicationCommand) []string {
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
 		names[i] = cmd.Name
 	}
 	return names
-}()
+}
+
+// refreshLanguageChoices builds the commands with the language choices of set-config matching the loaded locales,
+// commands registered afterwards offer them.
+func refreshLanguageChoices() {
+	cmds := make([]*discordgo.ApplicationCommand, len(commands))
+	for i, cmd := range commands {
+		cmds[i] = cmd
+		idx := slices.IndexFunc(cmd.Options, func(opt *discordgo.ApplicationCommandOption) bool { return opt.Name == "language" })
+		if idx < 0 {
+			continue
+		}
+		language := *cmd.Options[idx]
+		language.Choices = languageChoices()
+		withChoices := *cmd
+		withChoices.Options = slices.Clone(cmd.Options)
+		withChoices.Options[idx] = &language
+		cmds[i] = &withChoices
+	}
+	registeredCommands.Store(&cmds)
+}
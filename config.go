@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bot/i18n"
+	"bot/mechanics"
+	"bot/outbox"
+	"bot/watcher"
+
+	"github.com/BurntSushi/toml"
+	"github.com/kelseyhightower/envconfig"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is read from an optional yaml or toml file first and then from the environment, environment variables win.
+// File keys are the environment variable names in lower case.
+type Config struct {
+	DiscordBotToken       string        `envconfig:"DISCORD_BOT_TOKEN" yaml:"discord_bot_token" toml:"discord_bot_token"`
+	WLClientId            string        `envconfig:"WL_CLIENT_ID" yaml:"wl_client_id" toml:"wl_client_id"`
+	WLClientSecret        string        `envconfig:"WL_CLIENT_SECRET" yaml:"wl_client_secret" toml:"wl_client_secret"`
+	FFLogsClientId        string        `envconfig:"FFLOGS_CLIENT_ID" yaml:"fflogs_client_id" toml:"fflogs_client_id"`
+	FFLogsClientSecret    string        `envconfig:"FFLOGS_CLIENT_SECRET" yaml:"fflogs_client_secret" toml:"fflogs_client_secret"`
+	ESOLogsClientId       string        `envconfig:"ESOLOGS_CLIENT_ID" yaml:"esologs_client_id" toml:"esologs_client_id"`
+	ESOLogsClientSecret   string        `envconfig:"ESOLOGS_CLIENT_SECRET" yaml:"esologs_client_secret" toml:"esologs_client_secret"`
+	DBPath                string        `envconfig:"DB_PATH" yaml:"db_path" toml:"db_path"`
+	DefaultPollInterval   time.Duration `envconfig:"DEFAULT_POLL_INTERVAL" yaml:"default_poll_interval" toml:"default_poll_interval"`
+	HTTPAddr              string        `envconfig:"HTTP_ADDR" yaml:"http_addr" toml:"http_addr"`
+	PprofEnabled          bool          `envconfig:"PPROF_ENABLED" yaml:"pprof_enabled" toml:"pprof_enabled"`
+	PprofToken            string        `envconfig:"PPROF_TOKEN" yaml:"pprof_token" toml:"pprof_token"`
+	AdminToken            string        `envconfig:"ADMIN_TOKEN" yaml:"admin_token" toml:"admin_token"`
+	ReportErrors          bool          `envconfig:"REPORT_ERRORS_TO_LOG" yaml:"report_errors_to_log" toml:"report_errors_to_log"`
+	DeliveryRate          float64       `envconfig:"DISCORD_DELIVERY_RATE" yaml:"discord_delivery_rate" toml:"discord_delivery_rate"`
+	LeaderLockFile        string        `envconfig:"LEADER_LOCK_FILE" yaml:"leader_lock_file" toml:"leader_lock_file"`
+	OwnerId               string        `envconfig:"OWNER_ID" yaml:"owner_id" toml:"owner_id"`
+	OwnerGuildId          string        `envconfig:"OWNER_GUILD_ID" yaml:"owner_guild_id" toml:"owner_guild_id"`
+	OpsChannelId          string        `envconfig:"OPS_CHANNEL_ID" yaml:"ops_channel_id" toml:"ops_channel_id"`
+	FeedbackChannelId     string        `envconfig:"FEEDBACK_CHANNEL_ID" yaml:"feedback_channel_id" toml:"feedback_channel_id"`
+	PublicURL             string        `envconfig:"PUBLIC_URL" yaml:"public_url" toml:"public_url"`
+	LinkReplies           bool          `envconfig:"LINK_REPLIES" yaml:"link_replies" toml:"link_replies"`
+	WCLAlertThreshold     int           `envconfig:"WCL_ALERT_THRESHOLD" yaml:"wcl_alert_threshold" toml:"wcl_alert_threshold"`
+	LocalesDir            string        `envconfig:"LOCALES_DIR" yaml:"locales_dir" toml:"locales_dir"`
+	MechanicsFile         string        `envconfig:"MECHANICS_FILE" yaml:"mechanics_file" toml:"mechanics_file"`
+	BattleNetClientId     string        `envconfig:"BATTLENET_CLIENT_ID" yaml:"battlenet_client_id" toml:"battlenet_client_id"`
+	BattleNetClientSecret string        `envconfig:"BATTLENET_CLIENT_SECRET" yaml:"battlenet_client_secret" toml:"battlenet_client_secret"`
+	EventsURL             string        `envconfig:"EVENTS_URL" yaml:"events_url" toml:"events_url"`
+	EventsSecret          string        `envconfig:"EVENTS_SECRET" yaml:"events_secret" toml:"events_secret"`
+	GoogleCredentialsFile string        `envconfig:"GOOGLE_CREDENTIALS_FILE" yaml:"google_credentials_file" toml:"google_credentials_file"`
+	SlackBotToken         string        `envconfig:"SLACK_BOT_TOKEN" yaml:"slack_bot_token" toml:"slack_bot_token"`
+	TelegramBotToken      string        `envconfig:"TELEGRAM_BOT_TOKEN" yaml:"telegram_bot_token" toml:"telegram_bot_token"`
+	TwitchClientId        string        `envconfig:"TWITCH_CLIENT_ID" yaml:"twitch_client_id" toml:"twitch_client_id"`
+	TwitchClientSecret    string        `envconfig:"TWITCH_CLIENT_SECRET" yaml:"twitch_client_secret" toml:"twitch_client_secret"`
+	LogConfig             `yaml:",inline"`
+	ShardConfig           `yaml:",inline"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		DBPath:              "./store.db",
+		DefaultPollInterval: 1 * time.Minute,
+		HTTPAddr:            ":8080",
+		DeliveryRate:        5,
+		WCLAlertThreshold:   20,
+		LogConfig: LogConfig{
+			Level:              "debug",
+			Format:             "console",
+			SamplingInitial:    100,
+			SamplingThereafter: 100,
+		},
+		ShardConfig: ShardConfig{
+			ShardCount: "1",
+		},
+	}
+}
+
+// configPath returns the config file passed with -config or CONFIG_FILE.
+func configPath() string {
+	path := flag.String("config", os.Getenv("CONFIG_FILE"), "path to yaml or toml config file")
+	flag.Parse()
+	return *path
+}
+
+// loadConfig merges defaults, the config file and the environment. Files ending in .toml are read as toml,
+// any other as yaml.
+func loadConfig(path string) (Config, error) {
+	config := defaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("error reading config file: %w", err)
+		}
+		unmarshal := yaml.Unmarshal
+		if strings.EqualFold(filepath.Ext(path), ".toml") {
+			unmarshal = toml.Unmarshal
+		}
+		if err := unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("error parsing config file %v: %w", path, err)
+		}
+	}
+	if err := envconfig.Process("", &config); err != nil {
+		return config, err
+	}
+	return config, config.validate()
+}
+
+func (c Config) validate() error {
+	var errs []error
+	if c.DiscordBotToken == "" {
+		errs = append(errs, errors.New("discord_bot_token is required"))
+	}
+	if c.WLClientId == "" || c.WLClientSecret == "" {
+		errs = append(errs, errors.New("wl_client_id and wl_client_secret are required"))
+	}
+	if c.DeliveryRate <= 0 {
+		errs = append(errs, errors.New("discord_delivery_rate must be positive"))
+	}
+	if c.DefaultPollInterval < time.Minute {
+		errs = append(errs, errors.New("default_poll_interval must be at least a minute"))
+	}
+	return errors.Join(errs...)
+}
+
+// reloadable applies the settings that can change without a restart, the rest of the config is ignored.
+func reloadable(config Config, level zap.AtomicLevel, queue *outbox.Queue) error {
+	newLevel, err := zap.ParseAtomicLevel(config.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	level.SetLevel(newLevel.Level())
+	watcher.SetDefaultPollInterval(config.DefaultPollInterval)
+	queue.SetRate(config.DeliveryRate)
+	if config.LocalesDir != "" {
+		if err := i18n.LoadDir(config.LocalesDir); err != nil {
+			slog.Error("error loading locales", slog.String("dir", config.LocalesDir), "error", err)
+		}
+	}
+	if config.MechanicsFile != "" {
+		if err := mechanics.Load(config.MechanicsFile); err != nil {
+			slog.Error("error loading mechanics", slog.String("file", config.MechanicsFile), "error", err)
+		}
+	}
+	slog.Info("configuration reloaded",
+		slog.String("log_level", config.Level),
+		slog.Duration("default_poll_interval", config.DefaultPollInterval),
+		slog.Float64("delivery_rate", config.DeliveryRate),
+	)
+	return nil
+}
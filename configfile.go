@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bot/i18n"
+	"bot/storage"
+	"bot/warcraftlogs"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	configFileVersion = 1
+	maxConfigFileSize = 1 << 20
+)
+
+var errConfigTooLarge = errors.New("config file is too large")
+
+var attachmentClient = &http.Client{Timeout: 10 * time.Second}
+
+// configFile is the exported configuration of a server. Credentials and the integrations set up for the
+// server, like mirrors, spreadsheets and link channels, are left out, they belong to the server they were created for.
+type configFile struct {
+	Version int               `json:"version"`
+	Server  storage.Server    `json:"server"`
+	Claims  map[string]string `json:"claims,omitempty"`
+}
+
+func exportConfig(store *storage.Store, server storage.Server) ([]byte, error) {
+	claims, err := store.ReadClaims(server.ServerId)
+	if err != nil {
+		return nil, err
+	}
+	server.ServerId = ""
+	keepServerBound(&server, nil)
+	return json.MarshalIndent(configFile{Version: configFileVersion, Server: server, Claims: claims}, "", "  ")
+}
+
+func parseConfig(data []byte) (configFile, error) {
+	var cf configFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return cf, err
+	}
+	if cf.Version != configFileVersion {
+		return cf, fmt.Errorf("unsupported config version %d", cf.Version)
+	}
+	srv := cf.Server
+	var errs []error
+	if srv.WlGuildId <= 0 {
+		errs = append(errs, errors.New("wl_guild_id is required"))
+	}
+	if _, ok := warcraftlogs.SiteById(srv.Site); !ok {
+		errs = append(errs, fmt.Errorf("unknown site %q", srv.Site))
+	}
+	if !slices.Contains([]string{"", storage.EmbedModeDetailed, storage.EmbedModeCompact}, srv.EmbedMode) {
+		errs = append(errs, fmt.Errorf("unknown embed_mode %q", srv.EmbedMode))
+	}
+	if !slices.Contains([]string{"", storage.ThemeClassic, storage.ThemeDifficulty}, srv.Theme) {
+		errs = append(errs, fmt.Errorf("unknown theme %q", srv.Theme))
+	}
+	if !slices.Contains([]string{"", storage.LayoutEmbed, storage.LayoutComponents}, srv.Layout) {
+		errs = append(errs, fmt.Errorf("unknown layout %q", srv.Layout))
+	}
+	if !slices.Contains([]string{storage.SpoilersOff, storage.SpoilersTags, storage.SpoilersGeneric}, srv.SpoilerMode) {
+		errs = append(errs, fmt.Errorf("unknown spoiler_mode %q", srv.SpoilerMode))
+	}
+	if srv.WipeCutoff < int64(wipeCutoffMinValue) || srv.WipeCutoff > int64(wipeCutoffMaxValue) {
+		errs = append(errs, fmt.Errorf("wipe_cutoff must be between %v and %v", wipeCutoffMinValue, wipeCutoffMaxValue))
+	}
+	if srv.PollInterval != 0 && (srv.PollInterval < int64(pollIntervalMinValue) || srv.PollInterval > int64(pollIntervalMaxValue)) {
+		errs = append(errs, fmt.Errorf("poll_interval must be between %v and %v", pollIntervalMinValue, pollIntervalMaxValue))
+	}
+	if srv.Locale != "" && !slices.Contains(i18n.Locales(), discordgo.Locale(srv.Locale)) {
+		errs = append(errs, fmt.Errorf("unknown locale %q", srv.Locale))
+	}
+	if srv.Medals != nil && (len(srv.Medals) != 3 || slices.ContainsFunc(srv.Medals, func(m string) bool { return len(strings.Fields(m)) != 1 })) {
+		errs = append(errs, errors.New("medals must be three symbols"))
+	}
+	if utf8.RuneCountInString(srv.WebhookName) > 80 {
+		errs = append(errs, errors.New("webhook_name is longer than 80 characters"))
+	}
+	if srv.WebhookAvatar != "" {
+		if u, err := url.Parse(srv.WebhookAvatar); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, errors.New("webhook_avatar must be an https url"))
+		}
+	}
+	for character, userId := range cf.Claims {
+		if character == "" || !isSnowflake(userId) {
+			errs = append(errs, fmt.Errorf("invalid claim of %q by %q", character, userId))
+		}
+	}
+	return cf, errors.Join(errs...)
+}
+
+// keepServerBound replaces the settings belonging to the server they were set up for with the ones of src,
+// a nil src clears them.
+func keepServerBound(server *storage.Server, src *storage.Server) {
+	if src == nil {
+		src = &storage.Server{}
+	}
+	server.WebhookId, server.WebhookToken = src.WebhookId, src.WebhookToken
+	server.EventsURL, server.EventsSecret = src.EventsURL, src.EventsSecret
+	server.ApiToken = src.ApiToken
+	server.SpreadsheetId, server.SpreadsheetTab = src.SpreadsheetId, src.SpreadsheetTab
+	server.CalendarURL, server.CalendarAnnounce, server.ScheduledEvents = src.CalendarURL, src.CalendarAnnounce, src.ScheduledEvents
+	server.Mirrors = src.Mirrors
+	server.Streamers = src.Streamers
+	server.LinkChannels = src.LinkChannels
+	server.VerifiedClaims = src.VerifiedClaims
+}
+
+func isSnowflake(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
+
+// fetchAttachment downloads an attachment of an interaction, files larger than maxConfigFileSize are refused
+// instead of being cut off.
+func fetchAttachment(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := attachmentClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("attachment download: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxConfigFileSize {
+		return nil, errConfigTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		claims string
+		err    string
+	}{
+		{name: "minimal", server: `"wl_guild_id": 1, "wipe_cutoff": 4`},
+		{
+			name: "every setting",
+			server: `"wl_guild_id": 1, "site": "fflogs", "wipe_cutoff": 50, "poll_interval": 10, "embed_mode": "compact",
+				"theme": "difficulty", "layout": "components", "spoiler_mode": "spoiler", "locale": "de",
+				"medals": ["1.", "2.", "3."], "webhook_name": "Logs", "webhook_avatar": "https://example.com/a.png"`,
+			claims: `{"Thrall": "123456789012345678"}`,
+		},
+		{name: "missing guild", server: `"wipe_cutoff": 4`, err: "wl_guild_id is required"},
+		{name: "unknown site", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "site": "x"`, err: `unknown site "x"`},
+		{name: "unknown embed mode", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "embed_mode": "x"`, err: `unknown embed_mode "x"`},
+		{name: "unknown theme", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "theme": "x"`, err: `unknown theme "x"`},
+		{name: "unknown layout", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "layout": "x"`, err: `unknown layout "x"`},
+		{name: "unknown spoiler mode", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "spoiler_mode": "x"`, err: `unknown spoiler_mode "x"`},
+		{name: "wipe cutoff too low", server: `"wl_guild_id": 1, "wipe_cutoff": 0`, err: "wipe_cutoff must be between"},
+		{name: "wipe cutoff too high", server: `"wl_guild_id": 1, "wipe_cutoff": 51`, err: "wipe_cutoff must be between"},
+		{name: "poll interval too high", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "poll_interval": 11`, err: "poll_interval must be between"},
+		{name: "unknown locale", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "locale": "xx"`, err: `unknown locale "xx"`},
+		{name: "two medals", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "medals": ["a", "b"]`, err: "medals must be three symbols"},
+		{name: "medal with spaces", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "medals": ["a", "b", "c d"]`, err: "medals must be three symbols"},
+		{
+			name:   "long webhook name",
+			server: `"wl_guild_id": 1, "wipe_cutoff": 4, "webhook_name": "` + strings.Repeat("ä", 81) + `"`,
+			err:    "webhook_name is longer than 80 characters",
+		},
+		{name: "http avatar", server: `"wl_guild_id": 1, "wipe_cutoff": 4, "webhook_avatar": "http://example.com/a.png"`, err: "webhook_avatar must be an https url"},
+		{name: "invalid claim", server: `"wl_guild_id": 1, "wipe_cutoff": 4`, claims: `{"Thrall": "me"}`, err: `invalid claim of "Thrall" by "me"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `{"version": 1, "server": {` + tt.server + `}`
+			if tt.claims != "" {
+				data += `, "claims": ` + tt.claims
+			}
+			_, err := parseConfig([]byte(data + "}"))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseConfigVersion(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"version": 2, "server": {"wl_guild_id": 1, "wipe_cutoff": 4}}`)); err == nil {
+		t.Fatal("expected an error for an unsupported version")
+	}
+	if _, err := parseConfig([]byte(`not json`)); err == nil {
+		t.Fatal("expected an error for invalid json")
+	}
+}
+
+func TestParseConfigAllErrors(t *testing.T) {
+	_, err := parseConfig([]byte(`{"version": 1, "server": {"wipe_cutoff": 0, "theme": "x"}}`))
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"wl_guild_id is required", "wipe_cutoff must be between", `unknown theme "x"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't report %q", err, want)
+		}
+	}
+}
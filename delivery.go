@@ -0,0 +1,215 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"bot/errreport"
+	"bot/features"
+	"bot/metrics"
+	"bot/storage"
+	"bot/twitch"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// discordNotifier posts report updates to the channel of the server, or the raid thread of the report,
+// and edits the message of the report with later updates.
+type discordNotifier struct {
+	dg       *discordgo.Session
+	store    *storage.Store
+	twitch   *twitch.Client
+	guilds   *guildLookup
+	failures *errreport.Tracker
+	// messages, stats, modes and ranks are shared with the handlers restoring messages and answering buttons
+	messages *ttlcache.Cache[string, postedMessage]
+	stats    *ttlcache.Cache[string, watcher.StatsEvent]
+	modes    *ttlcache.Cache[string, string]
+	ranks    *ttlcache.Cache[string, string]
+}
+
+// postedMessage is the live message of a report and the webhook it was posted through, empty for the bot.
+// Webhook messages can only be edited through their webhook, and the layout of a message can not be changed by an edit.
+type postedMessage struct {
+	id           string
+	webhookId    string
+	componentsV2 bool
+}
+
+func (d *discordNotifier) Name() string {
+	return "discord"
+}
+
+func (d *discordNotifier) Notify(ctx context.Context, se watcher.StatsEvent) error {
+	if !featureEnabled(d.store, se.Server.ServerId, features.ComponentsLayout) {
+		se.Server.Layout = storage.LayoutEmbed
+	}
+	key := makeKey(se)
+	mode := embedModeOrDefault(se.Server.EmbedMode)
+	streams := liveStreams(d.twitch, se)
+	rankCtx, cancel := context.WithTimeout(ctx, 2500*time.Millisecond)
+	rank := d.guilds.killRank(rankCtx, se)
+	cancel()
+
+	item := d.messages.Get(key)
+	if item != nil && item.Value().webhookId != "" && item.Value().webhookId != se.Server.WebhookId {
+		// the webhook the message was posted through was removed, the report is posted again
+		d.messages.Delete(key)
+		item = nil
+	}
+	threadId, err := d.store.RaidThread(se.Server.ServerId, se.Server.ChannelId, se.ReportId)
+	if err != nil {
+		return fmt.Errorf("reading raid thread: %w", err)
+	}
+
+	if item != nil {
+		posted := item.Value()
+		if override := d.modes.Get(posted.id); override != nil {
+			mode = override.Value()
+		}
+		msg := constructReportMessage(se, mode, mentionClaims(d.store, se.Server), streams, rank)
+		if posted.componentsV2 == msg.componentsV2() {
+			err := editMessage(d.dg, se.Server, threadId, posted, msg)
+			metrics.DiscordMessages.WithLabelValues("edit", metrics.Result(err)).Inc()
+			d.track(se, "edit", err)
+			if err != nil {
+				return fmt.Errorf("updating message in channel %v: %w", se.Server.ChannelId, err)
+			}
+			d.stats.Set(posted.id, se, ttlcache.DefaultTTL)
+			d.ranks.Set(posted.id, rank, ttlcache.DefaultTTL)
+			return nil
+		}
+		// the layout was switched, the message is replaced by one in the new layout
+		err := deleteMessage(d.dg, se.Server, threadId, posted)
+		metrics.DiscordMessages.WithLabelValues("delete", metrics.Result(err)).Inc()
+		if err != nil {
+			slog.Warn("error deleting message of previous layout", slog.String("server", se.Server.ServerId), slog.String("message", posted.id), "error", err)
+		}
+		d.messages.Delete(key)
+	}
+
+	if se.Server.RaidThreads && threadId == "" {
+		var err error
+		threadId, err = openRaidThread(d.dg, se)
+		if err != nil {
+			// the report is posted to the channel instead
+			slog.Error("error starting raid thread", slog.String("server", se.Server.ServerId), slog.String("channel", se.Server.ChannelId), "error", err)
+		} else if err := d.store.SaveRaidThread(se.Server.ServerId, se.Server.ChannelId, se.ReportId, threadId); err != nil {
+			slog.Error("error saving raid thread", slog.String("server", se.Server.ServerId), slog.String("thread", threadId), "error", err)
+		}
+	}
+	msg := constructReportMessage(se, mode, mentionClaims(d.store, se.Server), streams, rank)
+	msgOut, err := postMessage(d.dg, se.Server, threadId, msg)
+	metrics.DiscordMessages.WithLabelValues("send", metrics.Result(err)).Inc()
+	d.track(se, "send", err)
+	if err != nil {
+		return fmt.Errorf("sending message to channel %v: %w", se.Server.ChannelId, err)
+	}
+	d.messages.Set(key, newPostedMessage(msgOut), ttlcache.DefaultTTL)
+	d.stats.Set(msgOut.ID, se, ttlcache.DefaultTTL)
+	d.ranks.Set(msgOut.ID, rank, ttlcache.DefaultTTL)
+	return nil
+}
+
+// track reports channels failing repeatedly, a single failure is usually a hiccup of the api.
+func (d *discordNotifier) track(se watcher.StatsEvent, operation string, err error) {
+	if err == nil {
+		d.failures.Succeed(se.Server.ChannelId)
+		return
+	}
+	d.failures.Fail(context.Background(), se.Server.ChannelId, err, map[string]string{
+		"component": "discord",
+		"operation": operation,
+		"server":    se.Server.ServerId,
+		"channel":   se.Server.ChannelId,
+		"report":    se.ReportId,
+	})
+}
+
+// postMessage sends a message to the channel of the server or to a thread in it, through its webhook when it has one.
+// Only the mentions of the message ping, text from reports can not ping everyone.
+func postMessage(s *discordgo.Session, server storage.Server, threadId string, msg reportMessage) (*discordgo.Message, error) {
+	if server.WebhookId != "" {
+		return s.WebhookThreadExecute(server.WebhookId, server.WebhookToken, true, threadId, &discordgo.WebhookParams{
+			Username:        server.WebhookName,
+			AvatarURL:       server.WebhookAvatar,
+			Embeds:          msg.embeds,
+			Components:      msg.components,
+			Flags:           msg.flags,
+			AllowedMentions: &discordgo.MessageAllowedMentions{Users: msg.mentions},
+		})
+	}
+	return s.ChannelMessageSendComplex(cmp.Or(threadId, server.ChannelId), &discordgo.MessageSend{
+		Embeds:          msg.embeds,
+		Components:      msg.components,
+		Flags:           msg.flags,
+		AllowedMentions: &discordgo.MessageAllowedMentions{Users: msg.mentions},
+	})
+}
+
+func newPostedMessage(msg *discordgo.Message) postedMessage {
+	return postedMessage{
+		id:           msg.ID,
+		webhookId:    msg.WebhookID,
+		componentsV2: msg.Flags&discordgo.MessageFlagsIsComponentsV2 != 0,
+	}
+}
+
+// editMessage edits a message posted with postMessage, through the webhook that posted it.
+// Edits never ping, the users were pinged when the message was posted.
+func editMessage(s *discordgo.Session, server storage.Server, threadId string, posted postedMessage, msg reportMessage) error {
+	messageId := posted.id
+	if posted.webhookId != "" {
+		edit := &discordgo.WebhookEdit{Components: &msg.components, AllowedMentions: &discordgo.MessageAllowedMentions{}}
+		if len(msg.embeds) > 0 {
+			edit.Embeds = &msg.embeds
+		}
+		if threadId != "" {
+			// discordgo has no thread variant of the webhook message edit
+			uri := discordgo.EndpointWebhookMessage(server.WebhookId, server.WebhookToken, messageId) + "?thread_id=" + threadId
+			_, err := s.RequestWithBucketID(http.MethodPatch, uri, edit, discordgo.EndpointWebhookToken("", ""))
+			return err
+		}
+		_, err := s.WebhookMessageEdit(server.WebhookId, server.WebhookToken, messageId, edit)
+		return err
+	}
+	edit := &discordgo.MessageEdit{
+		ID:              messageId,
+		Channel:         cmp.Or(threadId, server.ChannelId),
+		Components:      &msg.components,
+		Flags:           msg.flags,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+	if len(msg.embeds) > 0 {
+		edit.Embeds = &msg.embeds
+	}
+	_, err := s.ChannelMessageEditComplex(edit)
+	return err
+}
+
+// deleteMessage deletes a message posted with postMessage, through the webhook that posted it.
+func deleteMessage(s *discordgo.Session, server storage.Server, threadId string, posted postedMessage) error {
+	if posted.webhookId != "" {
+		uri := discordgo.EndpointWebhookMessage(server.WebhookId, server.WebhookToken, posted.id)
+		if threadId != "" {
+			uri += "?thread_id=" + threadId
+		}
+		_, err := s.RequestWithBucketID(http.MethodDelete, uri, nil, discordgo.EndpointWebhookToken("", ""))
+		return err
+	}
+	return s.ChannelMessageDelete(cmp.Or(threadId, server.ChannelId), posted.id)
+}
+
+// postedBy reports whether the message was posted for the server by the bot or by the webhook of the server.
+func postedBy(s *discordgo.Session, server storage.Server, msg *discordgo.Message) bool {
+	if server.WebhookId != "" && msg.WebhookID == server.WebhookId {
+		return true
+	}
+	return msg.Author != nil && msg.Author.ID == s.State.User.ID
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bot/i18n"
+	"bot/mechanics"
+	"bot/raiderio"
+	"bot/storage"
+	"bot/version"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	compactModeButtonId  = "embed-mode:" + storage.EmbedModeCompact
+	detailedModeButtonId = "embed-mode:" + storage.EmbedModeDetailed
+)
+
+// rank is the Raider.IO progression shown on prog kills, empty otherwise.
+func constructEmbed(stats watcher.StatsEvent, mode string, claims map[string]string, streams []liveStream, rank string) *discordgo.MessageEmbed {
+	locale := discordgo.Locale(stats.Server.Locale)
+	color := embedColor(stats)
+
+	var fields []*discordgo.MessageEmbedField
+	switch mode {
+	case storage.EmbedModeCompact:
+		fields = []*discordgo.MessageEmbedField{
+			{
+				Name:   formatTallyTitle(stats),
+				Value:  formatSnapshot(stats),
+				Inline: false,
+			},
+		}
+	default:
+		fields = []*discordgo.MessageEmbedField{
+			{
+				Name:   formatTallyTitle(stats),
+				Value:  formatBosses(stats.Bosses, stats.Server.SpoilerMode, locale),
+				Inline: false,
+			},
+			{
+				Name:   i18n.T(locale, "embed.top_first_deaths"),
+				Value:  formatTop(stats.TopFirstDeath, stats.Server.Medals, claims),
+				Inline: false,
+			},
+			{
+				Name:   i18n.T(locale, "embed.top_deaths"),
+				Value:  formatTop(stats.TopDeath, stats.Server.Medals, claims),
+				Inline: false,
+			},
+		}
+	}
+	if rank != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  i18n.T(locale, "summary.progress"),
+			Value: rank,
+		})
+	}
+	if len(streams) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  i18n.T(locale, "embed.live_on_twitch"),
+			Value: formatStreams(streams),
+		})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%v\n%v", siteName(stats.Server), stats.Title),
+		Description: i18n.T(locale, "embed.started_by", stats.StartedBy, discordTime(stats.StartedAt, 'f'), discordTime(stats.LastUpload, 'R')),
+		URL:         stats.URL,
+		Color:       color,
+		Fields:      fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: version.Version + " · " + i18n.T(locale, "embed.footer", formatInterval(locale, stats.PollInterval)),
+		},
+		Timestamp: stats.NextRefresh.Format(time.RFC3339),
+	}
+}
+
+const (
+	colorGreen  = 0x2ECC71
+	colorGrey   = 0x95A5A6
+	colorBlue   = 0x3498DB
+	colorPurple = 0x9B59B6
+	colorRed    = 0xE74C3C
+	colorGold   = 0xF1C40F
+)
+
+// siteName is the name of the log site of the server, servers with an unknown site get Warcraft Logs.
+func siteName(server storage.Server) string {
+	if site, ok := warcraftlogs.SiteById(server.Site); ok {
+		return site.Name
+	}
+	return warcraftlogs.Warcraft.Name
+}
+
+func embedColor(stats watcher.StatsEvent) int {
+	switch {
+	case !stats.Live:
+		return colorGrey
+	case stats.ProgKill && stats.Server.SpoilerMode == storage.SpoilersOff:
+		return colorRed
+	case stats.Server.Theme != storage.ThemeDifficulty:
+		return colorGreen
+	}
+	switch stats.Difficulty {
+	case warcraftlogs.DifficultyMythic:
+		return colorPurple
+	case warcraftlogs.DifficultyHeroic:
+		return colorBlue
+	case warcraftlogs.DifficultyNormal:
+		return colorGreen
+	default:
+		return colorGrey
+	}
+}
+
+func constructComponents(mode string, locale discordgo.Locale) []discordgo.MessageComponent {
+	isCompact := mode == storage.EmbedModeCompact
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    i18n.T(locale, "embed.compact"),
+					Style:    discordgo.SecondaryButton,
+					CustomID: compactModeButtonId,
+					Disabled: isCompact,
+				},
+				discordgo.Button{
+					Label:    i18n.T(locale, "embed.detailed"),
+					Style:    discordgo.SecondaryButton,
+					CustomID: detailedModeButtonId,
+					Disabled: !isCompact,
+				},
+			},
+		},
+	}
+}
+
+func formatSnapshot(stats watcher.StatsEvent) string {
+	locale := discordgo.Locale(stats.Server.Locale)
+	var sb strings.Builder
+	sb.WriteString("```")
+	sb.WriteString(padRight(i18n.T(locale, "embed.first_deaths"), 16))
+	sb.WriteString(formatLeader(stats.TopFirstDeath))
+	sb.WriteRune('\n')
+	sb.WriteString(padRight(i18n.T(locale, "embed.deaths"), 16))
+	sb.WriteString(formatLeader(stats.TopDeath))
+	sb.WriteString("```")
+	return sb.String()
+}
+
+func formatLeader(top []warcraftlogs.PlayerTop) string {
+	if len(top) == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%v (%d)", top[0].Name, top[0].Value)
+}
+
+func formatInterval(locale discordgo.Locale, d time.Duration) string {
+	if minutes := int(d.Minutes()); minutes > 1 {
+		return i18n.N(locale, "embed.every_minutes", minutes)
+	}
+	return i18n.T(locale, "embed.every_minute")
+}
+
+// discordTime renders t using Discord timestamp markup, so every viewer sees it in their own timezone.
+func discordTime(t time.Time, style rune) string {
+	return fmt.Sprintf("<t:%d:%c>", t.Unix(), style)
+}
+
+var defaultMedals = []string{"🥇", "🥈", "🥉"}
+
+// formatTop renders a leaderboard as a podium. Each row is its own code span rather than one code block,
+// since custom server emoji and mentions are not rendered inside code blocks.
+// claims maps lowercased character names to the discord users who claimed them.
+func formatTop(top []warcraftlogs.PlayerTop, medals []string, claims map[string]string) string {
+	if len(top) == 0 {
+		return "``` ```"
+	}
+	if len(medals) == 0 {
+		medals = defaultMedals
+	}
+	var sb strings.Builder
+	sb.Grow(256)
+	for i, t := range top {
+		if i < len(medals) {
+			sb.WriteString(medals[i])
+		} else {
+			sb.WriteString(fmt.Sprintf("**%d.**", i+1))
+		}
+		sb.WriteString(" `")
+		sb.WriteString(padRight(t.Name, 12))
+		sb.WriteString(padLeft(strconv.Itoa(t.Value), 12))
+		sb.WriteString("`")
+		if userId, ok := claims[strings.ToLower(t.Name)]; ok {
+			sb.WriteString(" <@")
+			sb.WriteString(userId)
+			sb.WriteString(">")
+		}
+		if i != len(top)-1 {
+			sb.WriteRune('\n')
+		}
+	}
+	return sb.String()
+}
+
+// formatTallyTitle returns the kill/wipe field name. Field names do not support markdown,
+// so spoiler-free servers only get the total pull count there.
+func formatTallyTitle(stats watcher.StatsEvent) string {
+	locale := discordgo.Locale(stats.Server.Locale)
+	if stats.Server.SpoilerMode != storage.SpoilersOff {
+		return i18n.N(locale, "embed.pulls_count", stats.Kills+stats.Wipes)
+	}
+	return i18n.N(locale, "embed.kills", stats.Kills) + " / " + i18n.N(locale, "embed.wipes", stats.Wipes)
+}
+
+func formatBosses(bosses []warcraftlogs.BossTally, spoilerMode string, locale discordgo.Locale) string {
+	if len(bosses) == 0 {
+		return "``` ```"
+	}
+	var sb strings.Builder
+	sb.Grow(128)
+	switch spoilerMode {
+	case storage.SpoilersGeneric:
+		sb.WriteString("```")
+		for i, b := range bosses {
+			sb.WriteString(padRight(i18n.T(locale, "embed.boss", i+1), 24))
+			sb.WriteString(padLeft(strconv.Itoa(b.Kills+b.Wipes), 8))
+			if i != len(bosses)-1 {
+				sb.WriteRune('\n')
+			}
+		}
+		sb.WriteString("```")
+	case storage.SpoilersTags:
+		for i, b := range bosses {
+			sb.WriteString("||`")
+			sb.WriteString(padRight(b.Name, 24))
+			sb.WriteString(padLeft(fmt.Sprintf("%d / %d", b.Kills, b.Wipes), 8))
+			sb.WriteString("`||")
+			if i != len(bosses)-1 {
+				sb.WriteRune('\n')
+			}
+		}
+	default:
+		sb.WriteString("```")
+		for i, b := range bosses {
+			sb.WriteString(padRight(b.Name, 24))
+			sb.WriteString(padLeft(fmt.Sprintf("%d / %d", b.Kills, b.Wipes), 8))
+			if i != len(bosses)-1 {
+				sb.WriteRune('\n')
+			}
+		}
+		sb.WriteString("```")
+	}
+	return sb.String()
+}
+
+func padRight(s string, to int) string {
+	if diff := to - utf8.RuneCountInString(s); diff > 0 {
+		return s + strings.Repeat(" ", diff)
+	}
+	return s
+}
+
+func padLeft(s string, to int) string {
+	if diff := to - utf8.RuneCountInString(s); diff > 0 {
+		return strings.Repeat(" ", diff) + s
+	}
+	return s
+}
+
+// summaryExtras is the summary data beyond the report stats, every part is optional.
+type summaryExtras struct {
+	Progress *raiderio.RaidProgress
+	Region   string
+	// Present and Raiders are the guild members in the report and the max level members of the roster.
+	Present   int
+	Raiders   int
+	Outsiders []string
+	Offenders []mechanics.Offender
+	// Kills are the kill fights of the report, offered for analysis on WoWAnalyzer.
+	Kills []warcraftlogs.Fight
+}
+
+func constructAnnounceEmbed(ae watcher.RaidAnnounceEvent) *discordgo.MessageEmbed {
+	locale := discordgo.Locale(ae.Server.Locale)
+	title := ae.Title
+	if title == "" {
+		title = i18n.T(locale, "announce.raid")
+	}
+	return &discordgo.MessageEmbed{
+		Title:       i18n.T(locale, "announce.title", title, discordTime(ae.Start, 'R')),
+		Description: i18n.T(locale, "announce.description", discordTime(ae.Start, 't')),
+		Color:       colorBlue,
+	}
+}
+
+func constructSummaryEmbed(stats watcher.StatsEvent, claims map[string]string, extras summaryExtras) *discordgo.MessageEmbed {
+	locale := discordgo.Locale(stats.Server.Locale)
+	mode := stats.Server.SpoilerMode
+
+	var kills, bestPulls []string
+	for i, b := range stats.Bosses {
+		label := bossLabel(b, i, mode, locale)
+		if b.Kills > 0 {
+			kills = append(kills, spoiler(label, mode))
+			continue
+		}
+		bestPulls = append(bestPulls, fmt.Sprintf("%v `%.1f%%` (%v)", label, b.BestPercent, i18n.N(locale, "summary.wipes", b.Wipes)))
+	}
+
+	mvp := "-"
+	if len(stats.TopDPS) > 0 {
+		mvp = fmt.Sprintf("**%v** %v", stats.TopDPS[0].Name, formatAmount(stats.TopDPS[0].Value))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       i18n.T(locale, "summary.title", stats.Title),
+		Description: fmt.Sprintf("%v – %v (%v)", discordTime(stats.StartedAt, 'f'), discordTime(stats.LastUpload, 't'), formatDuration(locale, stats.LastUpload.Sub(stats.StartedAt))),
+		URL:         stats.URL,
+		Color:       colorGold,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: i18n.T(locale, "summary.pulls"), Value: strconv.Itoa(stats.Kills + stats.Wipes), Inline: true},
+			{Name: i18n.T(locale, "summary.kills"), Value: spoiler(strconv.Itoa(stats.Kills), mode), Inline: true},
+			{Name: i18n.T(locale, "embed.deaths"), Value: strconv.Itoa(stats.TotalDeaths), Inline: true},
+			{Name: i18n.T(locale, "summary.bosses_killed"), Value: joinOrDash(kills), Inline: false},
+			{Name: i18n.T(locale, "summary.best_pulls"), Value: joinOrDash(bestPulls), Inline: false},
+			{Name: i18n.T(locale, "summary.mvp"), Value: mvp, Inline: false},
+			{Name: i18n.T(locale, "embed.top_deaths"), Value: formatTop(stats.TopDeath, stats.Server.Medals, claims), Inline: false},
+		},
+	}
+	if extras.Progress != nil && mode == storage.SpoilersOff {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  i18n.T(locale, "summary.progress"),
+			Value: formatProgress(locale, *extras.Progress, extras.Region),
+		})
+	}
+	if extras.Raiders > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   i18n.T(locale, "summary.attendance"),
+			Value:  fmt.Sprintf("%d/%d (%d%%)", extras.Present, extras.Raiders, extras.Present*100/extras.Raiders),
+			Inline: true,
+		})
+	}
+	if len(extras.Offenders) > 0 {
+		lines := make([]string, 0, len(extras.Offenders))
+		for _, o := range extras.Offenders {
+			lines = append(lines, fmt.Sprintf("**%v** %v · %v", o.Name, formatAmount(o.AvoidableDamage), i18n.N(locale, "summary.failures", o.Failures)))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  i18n.T(locale, "summary.avoidable"),
+			Value: strings.Join(lines, "\n"),
+		})
+	}
+	if len(extras.Outsiders) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   i18n.N(locale, "summary.outsiders", len(extras.Outsiders)),
+			Value:  truncate(strings.Join(extras.Outsiders, ", "), 1024),
+			Inline: true,
+		})
+	}
+	return embed
+}
+
+func bossLabel(boss warcraftlogs.BossTally, idx int, spoilerMode string, locale discordgo.Locale) string {
+	switch spoilerMode {
+	case storage.SpoilersGeneric:
+		return i18n.T(locale, "embed.boss", idx+1)
+	case storage.SpoilersTags:
+		return spoiler(boss.Name, spoilerMode)
+	default:
+		return boss.Name
+	}
+}
+
+func spoiler(s string, spoilerMode string) string {
+	if spoilerMode == storage.SpoilersOff {
+		return s
+	}
+	return "||" + s + "||"
+}
+
+func joinOrDash(lines []string) string {
+	if len(lines) == 0 {
+		return "-"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatDuration(locale discordgo.Locale, d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	return i18n.T(locale, "duration.hours_minutes", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func formatAmount(v int) string {
+	switch {
+	case v >= 1_000_000_000:
+		return fmt.Sprintf("%.2fB", float64(v)/1_000_000_000)
+	case v >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(v)/1_000_000)
+	case v >= 1_000:
+		return fmt.Sprintf("%.1fK", float64(v)/1_000)
+	default:
+		return strconv.Itoa(v)
+	}
+}
+
+type reportMessage struct {
+	embeds     []*discordgo.MessageEmbed
+	components []discordgo.MessageComponent
+	flags      discordgo.MessageFlags
+	// mentions are the users pinged when the message is posted, text components ping every mention otherwise
+	mentions []string
+}
+
+func (m reportMessage) componentsV2() bool {
+	return m.flags&discordgo.MessageFlagsIsComponentsV2 != 0
+}
+
+func constructReportMessage(stats watcher.StatsEvent, mode string, claims map[string]string, streams []liveStream, rank string) reportMessage {
+	locale := discordgo.Locale(stats.Server.Locale)
+	embed := constructEmbed(stats, mode, claims, streams, rank)
+	buttons := constructComponents(mode, locale)
+	if stats.Server.Layout != storage.LayoutComponents {
+		return reportMessage{
+			embeds:     []*discordgo.MessageEmbed{embed},
+			components: buttons,
+		}
+	}
+	return reportMessage{
+		components: []discordgo.MessageComponent{constructContainer(stats, embed, buttons)},
+		flags:      discordgo.MessageFlagsIsComponentsV2,
+		mentions:   mentionedUsers(stats, mode, claims),
+	}
+}
+
+// mentionedUsers returns the users whose claimed characters are in the death lists of the message.
+func mentionedUsers(stats watcher.StatsEvent, mode string, claims map[string]string) []string {
+	if mode == storage.EmbedModeCompact || len(claims) == 0 {
+		return nil
+	}
+	var users []string
+	for _, t := range slices.Concat(stats.TopFirstDeath, stats.TopDeath) {
+		if userId, ok := claims[strings.ToLower(t.Name)]; ok && !slices.Contains(users, userId) {
+			users = append(users, userId)
+		}
+	}
+	return users
+}
+
+// constructContainer lays the embed content out with the components v2 layout,
+// which unlike embed footers also renders the next refresh as relative time.
+func constructContainer(stats watcher.StatsEvent, embed *discordgo.MessageEmbed, buttons []discordgo.MessageComponent) discordgo.Container {
+	locale := discordgo.Locale(stats.Server.Locale)
+	divider := true
+
+	components := []discordgo.MessageComponent{
+		discordgo.Section{
+			Components: []discordgo.MessageComponent{
+				discordgo.TextDisplay{Content: fmt.Sprintf("### %v\n%v", stats.Title, embed.Description)},
+			},
+			Accessory: discordgo.Button{
+				Label: i18n.T(locale, "embed.open_report"),
+				Style: discordgo.LinkButton,
+				URL:   stats.URL,
+			},
+		},
+		discordgo.Separator{Divider: &divider},
+	}
+	for _, field := range embed.Fields {
+		components = append(components, discordgo.TextDisplay{Content: fmt.Sprintf("**%v**\n%v", field.Name, field.Value)})
+	}
+	components = append(components,
+		discordgo.Separator{Divider: &divider},
+		discordgo.TextDisplay{Content: fmt.Sprintf("-# %v %v", embed.Footer.Text, discordTime(stats.NextRefresh, 'R'))},
+	)
+	components = append(components, buttons...)
+
+	return discordgo.Container{
+		AccentColor: &embed.Color,
+		Components:  components,
+	}
+}
+
+// reportURL extracts the report link from a live report message of either layout.
+func reportURL(msg *discordgo.Message) (string, bool) {
+	if len(msg.Embeds) > 0 {
+		// summaries have no footer and must not replace the live message of the report
+		if msg.Embeds[0].Footer == nil {
+			return "", false
+		}
+		return msg.Embeds[0].URL, true
+	}
+	for _, component := range msg.Components {
+		container, ok := component.(*discordgo.Container)
+		if !ok {
+			continue
+		}
+		for _, inner := range container.Components {
+			section, ok := inner.(*discordgo.Section)
+			if !ok {
+				continue
+			}
+			if button, ok := section.Accessory.(*discordgo.Button); ok && button.URL != "" {
+				return button.URL, true
+			}
+		}
+	}
+	return "", false
+}
+
+// truncate cuts s to at most limit runes, marking the cut with an ellipsis.
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + "…"
+}
@@ -0,0 +1,107 @@
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// Reporter receives errors worth an operator's attention, implementations may forward them to Sentry, Rollbar and alike.
+type Reporter interface {
+	Report(ctx context.Context, err error, fields map[string]string)
+}
+
+type ReporterFunc func(ctx context.Context, err error, fields map[string]string)
+
+func (f ReporterFunc) Report(ctx context.Context, err error, fields map[string]string) {
+	f(ctx, err, fields)
+}
+
+var (
+	mu        sync.RWMutex
+	reporters []Reporter
+)
+
+func Register(r Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	reporters = append(reporters, r)
+}
+
+func Report(ctx context.Context, err error, fields map[string]string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, r := range reporters {
+		r.Report(ctx, err, fields)
+	}
+}
+
+// LogReporter writes reports to the default logger, useful when no external service is configured.
+type LogReporter struct{}
+
+func (LogReporter) Report(_ context.Context, err error, fields map[string]string) {
+	args := make([]any, 0, len(fields)*2+2)
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	args = append(args, "error", err)
+	slog.Error("reported error", args...)
+}
+
+// Tracker reports an operation once it has failed threshold times in a row, so transient errors stay in the logs only.
+type Tracker struct {
+	threshold int
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+func NewTracker(threshold int) *Tracker {
+	return &Tracker{threshold: threshold, failures: make(map[string]int)}
+}
+
+func (t *Tracker) Fail(ctx context.Context, key string, err error, fields map[string]string) {
+	t.mu.Lock()
+	t.failures[key]++
+	count := t.failures[key]
+	t.mu.Unlock()
+
+	if count == t.threshold {
+		Report(ctx, err, fields)
+	}
+}
+
+func (t *Tracker) Succeed(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+// Recover must be deferred directly, it reports a panic of the current goroutine instead of crashing the process.
+func Recover(ctx context.Context, fields map[string]string) {
+	if r := recover(); r != nil {
+		ReportPanic(ctx, r, fields)
+	}
+}
+
+// ReportPanic logs the recovered value with the stack trace and forwards it to the registered reporters.
+func ReportPanic(ctx context.Context, recovered any, fields map[string]string) {
+	err := fmt.Errorf("panic: %v", recovered)
+	stack := string(debug.Stack())
+
+	args := make([]any, 0, len(fields)*2+4)
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	args = append(args, "error", err, "stack", stack)
+	slog.Error("recovered from panic", args...)
+
+	withStack := make(map[string]string, len(fields)+1)
+	for k, v := range fields {
+		withStack[k] = v
+	}
+	withStack["stack"] = stack
+	Report(ctx, err, withStack)
+}
@@ -0,0 +1,360 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"bot/publicnet"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+const (
+	ReportStarted = "report.started"
+	ReportUpdated = "report.updated"
+	ReportEnded   = "report.ended"
+	BossKilled    = "boss.killed"
+)
+
+// Event is the body of a webhook request. Receivers verify it with the X-Signature-256 header,
+// "sha256=" followed by the hex HMAC-SHA256 of the body keyed with the shared secret.
+type Event struct {
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	ServerId  string    `json:"server_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Report    Report    `json:"report"`
+	Boss      *Boss     `json:"boss,omitempty"`
+}
+
+type Report struct {
+	Code          string    `json:"code"`
+	Title         string    `json:"title"`
+	Zone          string    `json:"zone"`
+	URL           string    `json:"url"`
+	Live          bool      `json:"live"`
+	Ended         bool      `json:"ended"`
+	StartedBy     string    `json:"started_by"`
+	StartedAt     time.Time `json:"started_at"`
+	LastUpload    time.Time `json:"last_upload"`
+	Kills         int       `json:"kills"`
+	Wipes         int       `json:"wipes"`
+	TotalDeaths   int       `json:"total_deaths"`
+	Bosses        []Boss    `json:"bosses"`
+	TopDeaths     []Player  `json:"top_deaths"`
+	TopFirstDeath []Player  `json:"top_first_deaths"`
+	TopDamage     []Player  `json:"top_damage,omitempty"`
+	LastPull      *Pull     `json:"last_pull,omitempty"`
+}
+
+type Pull struct {
+	Name       string  `json:"name"`
+	Difficulty int     `json:"difficulty"`
+	Kill       bool    `json:"kill"`
+	Percent    float64 `json:"percent"`
+	Deaths     int     `json:"deaths"`
+}
+
+type Boss struct {
+	EncounterId int     `json:"encounter_id"`
+	Name        string  `json:"name"`
+	Difficulty  int     `json:"difficulty"`
+	Kills       int     `json:"kills"`
+	Wipes       int     `json:"wipes"`
+	BestPercent float64 `json:"best_percent"`
+}
+
+type Player struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// Target is an endpoint receiving the events of a server.
+type Target struct {
+	URL    string
+	Secret string
+	// Restricted targets are set by server admins and may only be reached on public addresses.
+	Restricted bool
+}
+
+type delivery struct {
+	target Target
+	event  Event
+}
+
+const (
+	maxAttempts     = 4
+	queueSize       = 1000
+	targetQueueSize = 100
+	// workerIdleTimeout is how long the worker of a target waits for events before it stops.
+	workerIdleTimeout = 10 * time.Minute
+)
+
+// Dispatcher turns watcher updates into events and posts them to every target in order. Each target has a worker
+// of its own, so a slow or failing endpoint only delays its own events.
+type Dispatcher struct {
+	client     *http.Client
+	restricted *http.Client
+	queue      chan delivery
+	reports    *ttlcache.Cache[string, watcher.StatsEvent]
+}
+
+func NewDispatcher() *Dispatcher {
+	reports := ttlcache.New[string, watcher.StatsEvent](
+		ttlcache.WithTTL[string, watcher.StatsEvent](24*time.Hour),
+		ttlcache.WithDisableTouchOnHit[string, watcher.StatsEvent](),
+	)
+	go reports.Start()
+	return &Dispatcher{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		restricted: publicnet.NewClient(10 * time.Second),
+		queue:      make(chan delivery, queueSize),
+		reports:    reports,
+	}
+}
+
+// Handle derives the events of the update from the previous update of the report and queues them for the targets.
+// Reports are remembered in memory only, so after a restart the first update of a report is sent as started again.
+func (d *Dispatcher) Handle(se watcher.StatsEvent, targets []Target) {
+	key := se.Server.ServerId + se.ReportId
+	var prev *watcher.StatsEvent
+	if item := d.reports.Get(key); item != nil {
+		p := item.Value()
+		prev = &p
+	}
+	d.reports.Set(key, se, ttlcache.DefaultTTL)
+	if len(targets) == 0 {
+		return
+	}
+
+	report := NewReport(se)
+	var evs []Event
+	if prev == nil {
+		evs = append(evs, newEvent(ReportStarted, se, report, nil))
+	} else {
+		evs = append(evs, newEvent(ReportUpdated, se, report, nil))
+	}
+	for _, b := range se.Bosses {
+		if b.Kills > previousKills(prev, b) {
+			boss := newBoss(b)
+			evs = append(evs, newEvent(BossKilled, se, report, &boss))
+		}
+	}
+	if se.Ended && (prev == nil || !prev.Ended) {
+		evs = append(evs, newEvent(ReportEnded, se, report, nil))
+	}
+
+	for _, ev := range evs {
+		for _, t := range targets {
+			select {
+			case d.queue <- delivery{target: t, event: ev}:
+			default:
+				slog.Warn("event queue is full, dropping event", slog.String("server", ev.ServerId), slog.String("type", ev.Type))
+			}
+		}
+	}
+}
+
+// Run hands queued events to the workers of their targets until ctx is done. Workers are started with the first
+// event of a target and stop once they had nothing to deliver for workerIdleTimeout, so the workers of changed or
+// removed urls don't stay around.
+func (d *Dispatcher) Run(ctx context.Context) {
+	workers := make(map[string]chan delivery)
+	idle := make(chan string)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case dl := <-d.queue:
+			worker, ok := workers[dl.target.URL]
+			if !ok {
+				worker = make(chan delivery, targetQueueSize)
+				workers[dl.target.URL] = worker
+				go d.work(ctx, dl.target.URL, worker, idle)
+			}
+			select {
+			case worker <- dl:
+			default:
+				slog.Warn("event queue of target is full, dropping event", slog.String("server", dl.event.ServerId), slog.String("type", dl.event.Type))
+			}
+		case url := <-idle:
+			// only Run sends to the workers, a worker without queued events can't get any before it is closed
+			if worker, ok := workers[url]; ok && len(worker) == 0 {
+				delete(workers, url)
+				close(worker)
+			}
+		}
+	}
+}
+
+// work posts the events of one target one at a time, in order, until deliveries is closed. It reports on idle
+// when it had nothing to deliver for workerIdleTimeout.
+func (d *Dispatcher) work(ctx context.Context, url string, deliveries <-chan delivery, idle chan<- string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case dl, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			d.deliver(ctx, dl)
+		case <-time.After(workerIdleTimeout):
+			select {
+			case <-ctx.Done():
+				return
+			case idle <- url:
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dl delivery) {
+	body, err := json.Marshal(dl.event)
+	if err != nil {
+		slog.Error("error encoding event", "error", err)
+		return
+	}
+	logger := slog.With(slog.String("server", dl.event.ServerId), slog.String("type", dl.event.Type), slog.String("event", dl.event.Id))
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := d.post(ctx, dl.target, dl.event, body)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts {
+			logger.Error("error delivering event, giving up", slog.Int("attempts", attempt), "error", err)
+			return
+		}
+		logger.Warn("error delivering event, retrying", slog.Int("attempt", attempt), "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, target Target, ev Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "warcraftlogs-discord-bot")
+	req.Header.Set("X-Event-Type", ev.Type)
+	req.Header.Set("X-Event-Id", ev.Id)
+	req.Header.Set("X-Signature-256", Sign(target.Secret, body))
+	client := d.client
+	if target.Restricted {
+		client = d.restricted
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the X-Signature-256 header value of the body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newEvent(typ string, se watcher.StatsEvent, report Report, boss *Boss) Event {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return Event{
+		Id:        hex.EncodeToString(id),
+		Type:      typ,
+		ServerId:  se.Server.ServerId,
+		Timestamp: time.Now().UTC(),
+		Report:    report,
+		Boss:      boss,
+	}
+}
+
+func previousKills(prev *watcher.StatsEvent, boss warcraftlogs.BossTally) int {
+	if prev == nil {
+		return 0
+	}
+	for _, b := range prev.Bosses {
+		if b.EncounterID == boss.EncounterID && b.Difficulty == boss.Difficulty {
+			return b.Kills
+		}
+	}
+	return 0
+}
+
+// NewReport is the report of the stats as sent in events, the stats api serves it too.
+func NewReport(se watcher.StatsEvent) Report {
+	r := Report{
+		Code:          se.ReportId,
+		Title:         se.Title,
+		Zone:          se.Zone,
+		URL:           se.URL,
+		Live:          se.Live,
+		Ended:         se.Ended,
+		StartedBy:     se.StartedBy,
+		StartedAt:     se.StartedAt,
+		LastUpload:    se.LastUpload,
+		Kills:         se.Kills,
+		Wipes:         se.Wipes,
+		TotalDeaths:   se.TotalDeaths,
+		Bosses:        make([]Boss, 0, len(se.Bosses)),
+		TopDeaths:     newPlayers(se.TopDeath),
+		TopFirstDeath: newPlayers(se.TopFirstDeath),
+		TopDamage:     newPlayers(se.TopDPS),
+	}
+	for _, b := range se.Bosses {
+		r.Bosses = append(r.Bosses, newBoss(b))
+	}
+	if p := se.LastPull; p.Name != "" {
+		r.LastPull = &Pull{Name: p.Name, Difficulty: p.Difficulty, Kill: p.Kill, Percent: p.Percent, Deaths: p.Deaths}
+	}
+	return r
+}
+
+func newBoss(b warcraftlogs.BossTally) Boss {
+	return Boss{
+		EncounterId: b.EncounterID,
+		Name:        b.Name,
+		Difficulty:  b.Difficulty,
+		Kills:       b.Kills,
+		Wipes:       b.Wipes,
+		BestPercent: b.BestPercent,
+	}
+}
+
+func newPlayers(top []warcraftlogs.PlayerTop) []Player {
+	players := make([]Player, 0, len(top))
+	for _, t := range top {
+		players = append(players, Player{Name: t.Name, Value: t.Value})
+	}
+	return players
+}
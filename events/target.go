@@ -0,0 +1,25 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"bot/publicnet"
+)
+
+// ValidateURL checks a webhook url set by a server admin, it has to be https and resolve to public addresses only.
+// Deliveries check the addresses again when connecting, since the name may resolve differently by then.
+func ValidateURL(ctx context.Context, raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" || u.Hostname() == "" {
+		return nil, errors.New("url must be https with a host")
+	}
+	if err := publicnet.CheckHost(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"bot/sheets"
+	"bot/warcraftlogs"
+	"bot/watcher"
+)
+
+// sheetHeader names the columns of the rows written by exportRaid.
+var sheetHeader = []any{"Date", "Report", "Zone", "Player", "Class", "Deaths", "Parse", "Kills", "Wipes", "URL"}
+
+// exportRaid appends a row per player of the ended report to the spreadsheet of the server.
+func exportRaid(ctx context.Context, sheetsClient *sheets.Client, wlClient *warcraftlogs.Client, se watcher.StatsEvent) error {
+	players, err := wlClient.ReportPlayerStats(ctx, se.ReportId, se.Server.WipeCutoff)
+	if err != nil {
+		return err
+	}
+	date := se.StartedAt.Format("2006-01-02")
+	rows := make([][]any, 0, len(players))
+	for _, p := range players {
+		var parse any = ""
+		if p.Parse > 0 {
+			parse = math.Round(p.Parse*10) / 10
+		}
+		rows = append(rows, []any{date, se.Title, se.Zone, p.Name, p.Class, p.Deaths, parse, se.Kills, se.Wipes, se.URL})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return sheetsClient.Append(ctx, se.Server.SpreadsheetId, se.Server.SpreadsheetTab, rows)
+}
@@ -0,0 +1,42 @@
+package features
+
+// Flags gate features that are rolled out gradually. A flag is resolved from the server override,
+// then the global override, then its default.
+const (
+	ComponentsLayout  = "components-layout"
+	ReportSummary     = "report-summary"
+	MechanicsAnalysis = "mechanics-analysis"
+)
+
+type Flag struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+var Flags = []Flag{
+	{Name: ComponentsLayout, Description: "Allow the components layout for report messages", Default: true},
+	{Name: ReportSummary, Description: "Post a summary when a report ends", Default: true},
+	{Name: MechanicsAnalysis, Description: "Add avoidable damage from the mechanics file to summaries", Default: true},
+}
+
+func Lookup(name string) (Flag, bool) {
+	for _, f := range Flags {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+// Enabled resolves the flag from the server and global overrides.
+func Enabled(name string, global, server map[string]bool) bool {
+	if v, ok := server[name]; ok {
+		return v
+	}
+	if v, ok := global[name]; ok {
+		return v
+	}
+	f, _ := Lookup(name)
+	return f.Default
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"bot/storage"
+	"bot/version"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// feedbackCooldown is how long a user waits between two /feedback messages.
+const feedbackCooldown = 10 * time.Minute
+
+// constructFeedbackEmbed is the message the operator gets for /feedback, it is not translated.
+func constructFeedbackEmbed(i *discordgo.InteractionCreate, guildName string, server *storage.Server, text string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Title:       "Feedback",
+		Description: text,
+		Color:       colorBlue,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Server", Value: fmt.Sprintf("%v `%v`", guildName, i.GuildID), Inline: true},
+			{Name: "User", Value: fmt.Sprintf("<@%v> `%v`", i.Member.User.ID, i.Member.User.Username), Inline: true},
+			{Name: "Version", Value: fmt.Sprintf("%v · %v", version.Version, i.Locale), Inline: true},
+			{Name: "Configuration", Value: formatConfigSummary(server)},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
+
+// formatConfigSummary lists the settings of the server that matter for support, credentials are left out.
+func formatConfigSummary(server *storage.Server) string {
+	if server == nil {
+		return "not configured"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v guild `%v` in <#%v>\n", siteName(*server), server.WlGuildId, server.ChannelId)
+	fmt.Fprintf(&sb, "poll %v, wipe cutoff %v, locale %v\n", watcher.PollInterval(*server), server.WipeCutoff, cmp.Or(server.Locale, "auto"))
+	fmt.Fprintf(&sb, "embed %v, theme %v, layout %v, spoilers %v\n",
+		embedModeOrDefault(server.EmbedMode), cmp.Or(server.Theme, storage.ThemeClassic), cmp.Or(server.Layout, storage.LayoutEmbed), cmp.Or(server.SpoilerMode, "off"))
+	var extras []string
+	if server.WebhookId != "" {
+		extras = append(extras, "webhook")
+	}
+	if server.EventsURL != "" {
+		extras = append(extras, "events")
+	}
+	if server.SpreadsheetId != "" {
+		extras = append(extras, "sheet")
+	}
+	if server.CalendarURL != "" {
+		extras = append(extras, "calendar")
+	}
+	if server.ScheduledEvents {
+		extras = append(extras, "scheduled events")
+	}
+	if server.RaidThreads {
+		extras = append(extras, "raid threads")
+	}
+	if server.ApiToken != "" {
+		extras = append(extras, "stats api")
+	}
+	if server.TimeSeries {
+		extras = append(extras, "time series")
+	}
+	if server.VerifiedClaims {
+		extras = append(extras, "verified claims")
+	}
+	if len(server.LinkChannels) > 0 {
+		extras = append(extras, fmt.Sprintf("%d link channels", len(server.LinkChannels)))
+	}
+	if len(server.Streamers) > 0 {
+		extras = append(extras, fmt.Sprintf("%d streamers", len(server.Streamers)))
+	}
+	for _, platform := range slices.Sorted(maps.Keys(server.Mirrors)) {
+		extras = append(extras, platform+" mirror")
+	}
+	if len(extras) > 0 {
+		sb.WriteString(strings.Join(extras, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"bot/storage"
+	"bot/warcraftlogs"
+	"bot/watcher"
+)
+
+// grafanaMetrics are the series a grafana json datasource can query, best_percent returns one series per boss.
+var grafanaMetrics = []string{"deaths", "pulls", "kills", "wipes", "best_percent"}
+
+// newPoint is the time series point of an update.
+func newPoint(se watcher.StatsEvent) storage.Point {
+	p := storage.Point{
+		Time:   time.Now(),
+		Report: se.ReportId,
+		Deaths: se.TotalDeaths,
+		Kills:  se.Kills,
+		Wipes:  se.Wipes,
+	}
+	for _, b := range se.Bosses {
+		p.Bosses = append(p.Bosses, storage.BossPoint{Name: b.Name, Difficulty: b.Difficulty, Pulls: b.Kills + b.Wipes, BestPercent: b.BestPercent})
+	}
+	return p
+}
+
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaAPI struct {
+	store *storage.Store
+	auth  *statsAPI
+}
+
+// registerGrafanaAPI serves the time series of a server to the grafana json datasource plugin, the datasource url is
+// /grafana/servers/{id} with the stats api token as bearer token.
+func registerGrafanaAPI(mux *http.ServeMux, store *storage.Store, auth *statsAPI) {
+	api := &grafanaAPI{store: store, auth: auth}
+	mux.Handle("GET /grafana/servers/{id}", api.authorized(http.HandlerFunc(api.health)))
+	mux.Handle("GET /grafana/servers/{id}/{$}", api.authorized(http.HandlerFunc(api.health)))
+	mux.Handle("POST /grafana/servers/{id}/search", api.authorized(http.HandlerFunc(api.search)))
+	mux.Handle("POST /grafana/servers/{id}/metrics", api.authorized(http.HandlerFunc(api.metrics)))
+	mux.Handle("POST /grafana/servers/{id}/query", api.authorized(http.HandlerFunc(api.query)))
+}
+
+// grafanaURL is the datasource url of the server, a path when the public url of the bot is unknown.
+func grafanaURL(publicURL, serverId string) string {
+	return strings.TrimSuffix(publicURL, "/") + "/grafana/servers/" + serverId
+}
+
+func (api *grafanaAPI) authorized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !api.auth.authorized(r, r.PathValue("id")) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (api *grafanaAPI) health(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// search lists the metrics for older versions of the datasource.
+func (api *grafanaAPI) search(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, grafanaMetrics)
+}
+
+func (api *grafanaAPI) metrics(w http.ResponseWriter, _ *http.Request) {
+	type metric struct {
+		Label string `json:"label"`
+		Value string `json:"value"`
+	}
+	out := make([]metric, 0, len(grafanaMetrics))
+	for _, m := range grafanaMetrics {
+		out = append(out, metric{Label: m, Value: m})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (api *grafanaAPI) query(w http.ResponseWriter, r *http.Request) {
+	var q grafanaQuery
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	serverId := r.PathValue("id")
+	points, err := api.store.Points(serverId, q.Range.From, q.Range.To)
+	if err != nil {
+		slog.Error("error reading time series", slog.String("server", serverId), "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make([]grafanaSeries, 0, len(q.Targets))
+	for _, t := range q.Targets {
+		out = append(out, querySeries(t.Target, points)...)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// querySeries turns the points into the series of the metric, datapoints are value and unix milliseconds pairs.
+func querySeries(metric string, points []storage.Point) []grafanaSeries {
+	value := map[string]func(p storage.Point) int{
+		"deaths": func(p storage.Point) int { return p.Deaths },
+		"pulls":  func(p storage.Point) int { return p.Kills + p.Wipes },
+		"kills":  func(p storage.Point) int { return p.Kills },
+		"wipes":  func(p storage.Point) int { return p.Wipes },
+	}
+	if f, ok := value[metric]; ok {
+		series := grafanaSeries{Target: metric, Datapoints: make([][2]float64, 0, len(points))}
+		for _, p := range points {
+			series.Datapoints = append(series.Datapoints, [2]float64{float64(f(p)), float64(p.Time.UnixMilli())})
+		}
+		return []grafanaSeries{series}
+	}
+	if metric != "best_percent" {
+		return nil
+	}
+
+	bosses := make(map[string]*grafanaSeries)
+	for _, p := range points {
+		for _, b := range p.Bosses {
+			name := fmt.Sprintf("%v (%v)", b.Name, difficultyName(b.Difficulty))
+			if bosses[name] == nil {
+				bosses[name] = &grafanaSeries{Target: name}
+			}
+			bosses[name].Datapoints = append(bosses[name].Datapoints, [2]float64{b.BestPercent, float64(p.Time.UnixMilli())})
+		}
+	}
+	out := make([]grafanaSeries, 0, len(bosses))
+	for _, s := range bosses {
+		out = append(out, *s)
+	}
+	slices.SortFunc(out, func(a, b grafanaSeries) int { return cmp.Compare(a.Target, b.Target) })
+	return out
+}
+
+func difficultyName(difficulty int) string {
+	switch difficulty {
+	case warcraftlogs.DifficultyMythic:
+		return "mythic"
+	case warcraftlogs.DifficultyHeroic:
+		return "heroic"
+	case warcraftlogs.DifficultyNormal:
+		return "normal"
+	case warcraftlogs.DifficultyLFR:
+		return "lfr"
+	default:
+		return strconv.Itoa(difficulty)
+	}
+}
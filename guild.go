@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"bot/battlenet"
+	"bot/i18n"
+	"bot/raiderio"
+	"bot/storage"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// guildLookup resolves the Raider.IO profile and the in-game roster of a configured Warcraft Logs guild.
+// bnetClient is nil when no Battle.net credentials are configured.
+type guildLookup struct {
+	wlClient   *warcraftlogs.Client
+	rioClient  *raiderio.Client
+	bnetClient *battlenet.Client
+	guilds     *ttlcache.Cache[int64, warcraftlogs.Guild]
+}
+
+func newGuildLookup(wlClient *warcraftlogs.Client, rioClient *raiderio.Client, bnetClient *battlenet.Client) *guildLookup {
+	guilds := ttlcache.New[int64, warcraftlogs.Guild](
+		ttlcache.WithTTL[int64, warcraftlogs.Guild](24 * time.Hour),
+	)
+	go guilds.Start()
+	return &guildLookup{wlClient: wlClient, rioClient: rioClient, bnetClient: bnetClient, guilds: guilds}
+}
+
+func (g *guildLookup) guild(ctx context.Context, wlGuildId int64) (warcraftlogs.Guild, error) {
+	if item := g.guilds.Get(wlGuildId); item != nil {
+		return item.Value(), nil
+	}
+	guild, err := g.wlClient.Guild(ctx, wlGuildId)
+	if err != nil {
+		return warcraftlogs.Guild{}, err
+	}
+	g.guilds.Set(wlGuildId, guild, ttlcache.DefaultTTL)
+	return guild, nil
+}
+
+func (g *guildLookup) profile(ctx context.Context, wlGuildId int64) (raiderio.GuildProfile, error) {
+	guild, err := g.guild(ctx, wlGuildId)
+	if err != nil {
+		return raiderio.GuildProfile{}, err
+	}
+	return g.rioClient.GuildProfile(ctx, guild.Region, guild.Realm, guild.Name)
+}
+
+// isWarcraft reports whether the server watches a World of Warcraft guild, the only one Raider.IO and Battle.net know.
+func isWarcraft(server storage.Server) bool {
+	site, _ := warcraftlogs.SiteById(server.Site)
+	return site == warcraftlogs.Warcraft
+}
+
+func (g *guildLookup) rosterEnabled() bool {
+	return g.bnetClient != nil
+}
+
+func (g *guildLookup) roster(ctx context.Context, wlGuildId int64) (battlenet.Roster, error) {
+	guild, err := g.guild(ctx, wlGuildId)
+	if err != nil {
+		return battlenet.Roster{}, err
+	}
+	if guild.MemberName == "" {
+		return battlenet.Roster{}, fmt.Errorf("no member of guild %v is known", guild.Name)
+	}
+	href, err := g.bnetClient.GuildHref(ctx, guild.Region, guild.MemberRealm, guild.MemberName)
+	if err != nil {
+		return battlenet.Roster{}, err
+	}
+	return g.bnetClient.Roster(ctx, href)
+}
+
+// formatProgress renders the progress summary with the ranks the guild has, e.g. "**6/8 M** · World #1234 · EU #321".
+func formatProgress(locale discordgo.Locale, rp raiderio.RaidProgress, region string) string {
+	parts := []string{"**" + rp.Summary + "**"}
+	if rp.Rank.World > 0 {
+		parts = append(parts, i18n.T(locale, "progress.world", rp.Rank.World))
+	}
+	if rp.Rank.Region > 0 {
+		parts = append(parts, i18n.T(locale, "progress.region", strings.ToUpper(region), rp.Rank.Region))
+	}
+	if rp.Rank.Realm > 0 {
+		parts = append(parts, i18n.T(locale, "progress.realm", rp.Rank.Realm))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// killRank returns the Raider.IO progression of the guild in the raid of a live prog kill, rendered for the
+// report embed. It is empty for other updates, for servers hiding kills and when Raider.IO is unavailable.
+func (g *guildLookup) killRank(ctx context.Context, se watcher.StatsEvent) string {
+	if !se.Live || !se.ProgKill || !isWarcraft(se.Server) || se.Server.SpoilerMode != storage.SpoilersOff {
+		return ""
+	}
+	profile, err := g.profile(ctx, se.Server.WlGuildId)
+	if err != nil {
+		slog.Warn("error loading raider.io profile", slog.String("server", se.Server.ServerId), "error", err)
+		return ""
+	}
+	rp, ok := profile.Raid(se.Zone)
+	if !ok {
+		return ""
+	}
+	return formatProgress(discordgo.Locale(se.Server.Locale), rp, profile.Region)
+}
+
+// raidName turns a Raider.IO raid slug back into a readable name.
+func raidName(slug string) string {
+	words := strings.Split(slug, "-")
+	for i, w := range words {
+		if len(w) > 2 || i == 0 {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// summaryExtras collects the Raider.IO progression and the roster based attendance for the summary,
+// failures only cost the summary the affected fields.
+func (g *guildLookup) summaryExtras(ctx context.Context, se watcher.StatsEvent) summaryExtras {
+	var extras summaryExtras
+	if !isWarcraft(se.Server) {
+		return extras
+	}
+	profile, err := g.profile(ctx, se.Server.WlGuildId)
+	if err != nil {
+		slog.Warn("error loading raider.io profile", slog.String("server", se.Server.ServerId), "error", err)
+	} else if rp, ok := profile.Raid(se.Zone); ok {
+		extras.Progress = &rp
+		extras.Region = profile.Region
+	}
+
+	fights, err := g.wlClient.GetBossFights(ctx, se.ReportId)
+	if err != nil {
+		slog.Warn("error loading report fights", slog.String("server", se.Server.ServerId), slog.String("report", se.ReportId), "error", err)
+	} else {
+		extras.Kills = killFights(fights)
+	}
+
+	if !g.rosterEnabled() {
+		return extras
+	}
+	roster, err := g.roster(ctx, se.Server.WlGuildId)
+	if err != nil {
+		slog.Warn("error loading guild roster", slog.String("server", se.Server.ServerId), "error", err)
+		return extras
+	}
+	players, err := g.wlClient.ReportPlayers(ctx, se.ReportId)
+	if err != nil {
+		slog.Warn("error loading report players", slog.String("server", se.Server.ServerId), slog.String("report", se.ReportId), "error", err)
+		return extras
+	}
+	raiders := roster.Raiders()
+	extras.Raiders = len(raiders)
+	for _, p := range players {
+		if _, ok := roster.Find(p, ""); ok {
+			if slices.ContainsFunc(raiders, func(m battlenet.Member) bool { return strings.EqualFold(m.Name, p) }) {
+				extras.Present++
+			}
+			continue
+		}
+		extras.Outsiders = append(extras.Outsiders, p)
+	}
+	return extras
+}
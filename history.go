@@ -0,0 +1,169 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"bot/i18n"
+	"bot/storage"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// backfillMaxPages caps the reports a backfill reads, a page holds 100.
+	backfillMaxPages = 50
+	leaderboardSize  = 10
+)
+
+// recordHistory keeps the summary of an ended report for the leaderboard.
+func recordHistory(ctx context.Context, store *storage.Store, client *warcraftlogs.Client, se watcher.StatsEvent) error {
+	players, err := client.ReportPlayerStats(ctx, se.ReportId, se.Server.WipeCutoff)
+	if err != nil {
+		return err
+	}
+	return store.SaveReportSummary(se.Server.ServerId, storage.ReportSummary{
+		Code:      se.ReportId,
+		Title:     se.Title,
+		Zone:      se.Zone,
+		StartedAt: se.StartedAt,
+		Kills:     se.Kills,
+		Wipes:     se.Wipes,
+		Players:   playerSummaries(players),
+	})
+}
+
+// backfillHistory imports the raid reports of the current tier that are not stored yet and returns how many were
+// imported. The current tier is the newest raid zone the guild logged, zone ids grow with every tier, so farm runs
+// of older raids don't count. Reports are paged back until a whole page holds no report of the tier, the guild was
+// raiding the previous tier then.
+func backfillHistory(ctx context.Context, store *storage.Store, client *warcraftlogs.Client, server storage.Server) (int, error) {
+	var (
+		reports []warcraftlogs.Report
+		tier    warcraftlogs.Zone
+	)
+	for page := 1; page <= backfillMaxPages; page++ {
+		batch, more, err := client.GuildReports(ctx, server.WlGuildId, time.UnixMilli(0), page)
+		if err != nil {
+			return 0, err
+		}
+		inTier := false
+		for _, r := range batch {
+			if !client.Site().IsRaid(r.Zone) || r.Zone.ID < tier.ID {
+				continue
+			}
+			if r.Zone.ID > tier.ID {
+				tier = r.Zone
+				reports = reports[:0]
+			}
+			reports = append(reports, r)
+			inTier = true
+		}
+		if !more || (!inTier && tier.ID != 0) {
+			break
+		}
+	}
+
+	imported := 0
+	for _, r := range reports {
+		found, err := store.HasReportSummary(server.ServerId, r.Code)
+		if err != nil {
+			return imported, err
+		}
+		if found {
+			continue
+		}
+		fights, err := client.GetBossFights(ctx, r.Code)
+		if err != nil {
+			return imported, err
+		}
+		players, err := client.ReportPlayerStats(ctx, r.Code, server.WipeCutoff)
+		if err != nil {
+			return imported, err
+		}
+		rs := storage.ReportSummary{
+			Code:      r.Code,
+			Title:     r.Title,
+			Zone:      r.Zone.Name,
+			StartedAt: time.UnixMilli(r.StartTime),
+			Players:   playerSummaries(players),
+		}
+		for _, f := range fights {
+			if f.Kill {
+				rs.Kills++
+			} else {
+				rs.Wipes++
+			}
+		}
+		if err := store.SaveReportSummary(server.ServerId, rs); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+func playerSummaries(players []warcraftlogs.PlayerStats) []storage.PlayerSummary {
+	out := make([]storage.PlayerSummary, 0, len(players))
+	for _, p := range players {
+		out = append(out, storage.PlayerSummary{Name: p.Name, Class: p.Class, Deaths: p.Deaths, Parse: p.Parse})
+	}
+	return out
+}
+
+// constructLeaderboardEmbed ranks the players over the raid nights of the newest tier by deaths and by average parse.
+func constructLeaderboardEmbed(locale discordgo.Locale, server storage.Server, summaries []storage.ReportSummary) *discordgo.MessageEmbed {
+	tier := summaries[0].Zone
+	type total struct {
+		deaths, parsed int
+		parse          float64
+	}
+	totals := make(map[string]*total)
+	nights := 0
+	for _, rs := range summaries {
+		if rs.Zone != tier {
+			continue
+		}
+		nights++
+		for _, p := range rs.Players {
+			t := totals[p.Name]
+			if t == nil {
+				t = &total{}
+				totals[p.Name] = t
+			}
+			t.deaths += p.Deaths
+			if p.Parse > 0 {
+				t.parse += p.Parse
+				t.parsed++
+			}
+		}
+	}
+
+	var deaths, parses []warcraftlogs.PlayerTop
+	for name, t := range totals {
+		if t.deaths > 0 {
+			deaths = append(deaths, warcraftlogs.PlayerTop{Name: name, Value: t.deaths})
+		}
+		if t.parsed > 0 {
+			parses = append(parses, warcraftlogs.PlayerTop{Name: name, Value: int(math.Round(t.parse / float64(t.parsed)))})
+		}
+	}
+	byValue := func(a, b warcraftlogs.PlayerTop) int { return cmp.Or(b.Value-a.Value, cmp.Compare(a.Name, b.Name)) }
+	slices.SortFunc(deaths, byValue)
+	slices.SortFunc(parses, byValue)
+
+	return &discordgo.MessageEmbed{
+		Title: i18n.T(locale, "leaderboard.title", tier),
+		Color: colorGold,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: i18n.T(locale, "leaderboard.deaths"), Value: formatTop(deaths[:min(len(deaths), leaderboardSize)], server.Medals, nil)},
+			{Name: i18n.T(locale, "leaderboard.parses"), Value: formatTop(parses[:min(len(parses), leaderboardSize)], server.Medals, nil)},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: i18n.N(locale, "leaderboard.nights", nights)},
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"bot/warcraftlogs"
+
+	"github.com/bwmarrin/discordgo"
+	bolt "go.etcd.io/bbolt"
+)
+
+func serveHTTP(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("http server is listening", slog.String("addr", addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("http server stopped", "error", err)
+		}
+	}()
+	return srv
+}
+
+type readinessCheck func() error
+
+func healthHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"status":"ok"}`))
+}
+
+func readyHandler(checks map[string]readinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(); err != nil {
+				status = http.StatusServiceUnavailable
+				results[name] = err.Error()
+				continue
+			}
+			results[name] = "ok"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(results)
+	}
+}
+
+func discordReady(sessions []*discordgo.Session) readinessCheck {
+	return func() error {
+		for _, s := range sessions {
+			s.RLock()
+			ready := s.DataReady
+			s.RUnlock()
+			if !ready {
+				return fmt.Errorf("gateway shard %d is not connected", s.ShardID)
+			}
+		}
+		return nil
+	}
+}
+
+func dbReady(db *bolt.DB) readinessCheck {
+	return func() error {
+		return db.View(func(tx *bolt.Tx) error { return nil })
+	}
+}
+
+func wclReady(client *warcraftlogs.Client) readinessCheck {
+	return func() error {
+		if !client.TokenValid() {
+			return errors.New("token is expired")
+		}
+		return nil
+	}
+}
+
+// requireToken rejects requests without the bearer token, an empty token disables the check.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func registerPprof(mux *http.ServeMux, token string) {
+	mux.Handle("/debug/pprof/", requireToken(token, http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", requireToken(token, http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", requireToken(token, http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", requireToken(token, http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", requireToken(token, http.HandlerFunc(pprof.Trace)))
+}
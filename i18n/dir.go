@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"time"
+)
+
+// LoadDir replaces the catalog with the bundled locales merged with the <locale>.json and <locale>.toml files of dir.
+// Keys of a file override the bundled ones of its locale, files of new locales add languages.
+// Invalid files are skipped and reported in the returned error.
+func LoadDir(dir string) error {
+	merged, err := readBundles(bundled, "locales")
+	if err != nil {
+		return err
+	}
+	extra, err := readBundles(os.DirFS(dir), ".")
+	for locale, b := range extra {
+		if merged[locale] == nil {
+			merged[locale] = Bundle{}
+		}
+		maps.Copy(merged[locale], b)
+	}
+
+	mu.Lock()
+	added := !maps.EqualFunc(bundles, merged, func(Bundle, Bundle) bool { return true })
+	bundles = merged
+	onChange := localesChanged
+	mu.Unlock()
+	slog.Info("locales loaded", slog.String("dir", dir), slog.Int("files", len(extra)))
+	if added && onChange != nil {
+		onChange()
+	}
+	return err
+}
+
+// OnLocalesChanged sets the function called after a load adds or removes a locale.
+func OnLocalesChanged(fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	localesChanged = fn
+}
+
+// WatchDir reloads the directory whenever a file in it changes, until ctx is done.
+func WatchDir(ctx context.Context, dir string, interval time.Duration) {
+	last := dirModTime(dir)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		mod := dirModTime(dir)
+		if mod.Equal(last) {
+			continue
+		}
+		last = mod
+		if err := LoadDir(dir); err != nil {
+			slog.Error("error reloading locales", slog.String("dir", dir), "error", err)
+		}
+	}
+}
+
+// dirModTime returns the latest modification time of the directory and its files,
+// so both added or removed files and edits in place are noticed.
+func dirModTime(dir string) time.Time {
+	var latest time.Time
+	if info, err := os.Stat(dir); err == nil {
+		latest = info.ModTime()
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
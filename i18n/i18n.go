@@ -0,0 +1,189 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Fallback is the last locale of every fallback chain, every key must exist in it.
+const Fallback = discordgo.EnglishUS
+
+//go:embed locales/*.json
+var bundled embed.FS
+
+// Bundle maps message keys to messages of a single locale.
+type Bundle map[string]Message
+
+// Message is a fmt format string, or a set of them keyed by plural category ("one", "few", "many", "other")
+// for messages with a count.
+type Message map[string]string
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*m = Message{"other": text}
+		return nil
+	}
+	forms := map[string]string{}
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return err
+	}
+	if _, ok := forms["other"]; !ok {
+		return errors.New("plural message without the other form")
+	}
+	*m = forms
+	return nil
+}
+
+var (
+	mu             sync.RWMutex
+	bundles        = map[discordgo.Locale]Bundle{}
+	localesChanged func()
+)
+
+func init() {
+	b, err := readBundles(bundled, "locales")
+	if err != nil {
+		panic(err)
+	}
+	bundles = b
+}
+
+// readBundles reads every <locale>.json and <locale>.toml file of the directory.
+func readBundles(fsys fs.FS, dir string) (map[discordgo.Locale]Bundle, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[discordgo.Locale]Bundle)
+	var errs []error
+	for _, e := range entries {
+		ext := path.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".toml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var b Bundle
+		if ext == ".toml" {
+			b, err = parseTOML(data)
+		} else {
+			err = json.Unmarshal(data, &b)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid locale file %v: %w", e.Name(), err))
+			continue
+		}
+		locale := discordgo.Locale(strings.TrimSuffix(e.Name(), ext))
+		if out[locale] == nil {
+			out[locale] = Bundle{}
+		}
+		maps.Copy(out[locale], b)
+	}
+	return out, errors.Join(errs...)
+}
+
+// T formats the message of the key in the first locale of the fallback chain that has it,
+// or returns the key itself when no locale has it.
+func T(locale discordgo.Locale, key string, args ...any) string {
+	msg, _, ok := lookup(locale, key)
+	if !ok {
+		return key
+	}
+	return format(msg["other"], args)
+}
+
+// N is T for messages with a count, the plural form is chosen by n. Without args n is the only argument.
+func N(locale discordgo.Locale, key string, n int, args ...any) string {
+	msg, resolved, ok := lookup(locale, key)
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		args = []any{n}
+	}
+	form, ok := msg[pluralCategory(resolved, n)]
+	if !ok {
+		form = msg["other"]
+	}
+	return format(form, args)
+}
+
+func format(format string, args []any) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Locales returns the locales with a bundle, sorted.
+func Locales() []discordgo.Locale {
+	mu.RLock()
+	defer mu.RUnlock()
+	locales := make([]discordgo.Locale, 0, len(bundles))
+	for locale := range bundles {
+		locales = append(locales, locale)
+	}
+	slices.Sort(locales)
+	return locales
+}
+
+// Supported reports whether the locale or another region of its language has a bundle.
+func Supported(locale discordgo.Locale) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	for l := range bundles {
+		if language(l) == language(locale) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookup(locale discordgo.Locale, key string) (Message, discordgo.Locale, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, l := range fallbackChain(locale) {
+		if msg, ok := bundles[l][key]; ok {
+			return msg, l, true
+		}
+	}
+	return nil, "", false
+}
+
+// fallbackChain returns the locale, the bundles of the same language in other regions (es-419 → es-ES),
+// and the Fallback locale. The caller must hold mu.
+func fallbackChain(locale discordgo.Locale) []discordgo.Locale {
+	chain := []discordgo.Locale{locale}
+	lang := language(locale)
+	var regional []discordgo.Locale
+	for l := range bundles {
+		if l != locale && l != Fallback && language(l) == lang {
+			regional = append(regional, l)
+		}
+	}
+	slices.Sort(regional)
+	chain = append(chain, regional...)
+	if locale != Fallback {
+		chain = append(chain, Fallback)
+	}
+	return chain
+}
+
+func language(locale discordgo.Locale) string {
+	lang, _, _ := strings.Cut(string(locale), "-")
+	return lang
+}
@@ -0,0 +1,102 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		locale discordgo.Locale
+		n      int
+		want   string
+	}{
+		{discordgo.EnglishUS, 0, "other"},
+		{discordgo.EnglishUS, 1, "one"},
+		{discordgo.EnglishUS, 2, "other"},
+		{discordgo.EnglishUS, -1, "one"},
+		{discordgo.German, 1, "one"},
+		{discordgo.German, 21, "other"},
+		{discordgo.French, 0, "one"},
+		{discordgo.French, 1, "one"},
+		{discordgo.French, 2, "other"},
+		{discordgo.PortugueseBR, 0, "one"},
+		{discordgo.Russian, 1, "one"},
+		{discordgo.Russian, 2, "few"},
+		{discordgo.Russian, 5, "many"},
+		{discordgo.Russian, 11, "many"},
+		{discordgo.Russian, 12, "many"},
+		{discordgo.Russian, 21, "one"},
+		{discordgo.Russian, 22, "few"},
+		{discordgo.Russian, 111, "many"},
+		{discordgo.Ukrainian, 101, "one"},
+		{discordgo.Polish, 1, "one"},
+		{discordgo.Polish, 21, "many"},
+		{discordgo.Polish, 22, "few"},
+		{discordgo.Polish, 14, "many"},
+		{discordgo.Japanese, 1, "other"},
+		{discordgo.Korean, 1, "other"},
+	}
+	for _, tt := range tests {
+		if got := pluralCategory(tt.locale, tt.n); got != tt.want {
+			t.Errorf("pluralCategory(%v, %v) = %v, want %v", tt.locale, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	mu.Lock()
+	saved := bundles
+	bundles = map[discordgo.Locale]Bundle{
+		Fallback: {
+			"greeting": {"other": "hello"},
+			"english":  {"other": "only english"},
+			"raids":    {"one": "%d raid", "other": "%d raids"},
+		},
+		discordgo.SpanishES: {
+			"greeting": {"other": "hola"},
+			"spain":    {"other": "solo españa"},
+		},
+		discordgo.Russian: {
+			"raids": {"one": "%d рейд", "few": "%d рейда", "other": "%d рейдов"},
+		},
+	}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		bundles = saved
+		mu.Unlock()
+	})
+
+	tests := []struct {
+		name   string
+		locale discordgo.Locale
+		key    string
+		n      int
+		want   string
+	}{
+		{"own locale", discordgo.SpanishES, "greeting", -1, "hola"},
+		{"other region of the language", discordgo.SpanishLATAM, "greeting", -1, "hola"},
+		{"region before fallback", discordgo.SpanishLATAM, "spain", -1, "solo españa"},
+		{"fallback locale", discordgo.SpanishES, "english", -1, "only english"},
+		{"unsupported locale", discordgo.Japanese, "greeting", -1, "hello"},
+		{"missing key", discordgo.SpanishES, "missing", -1, "missing"},
+		{"plural of the locale", discordgo.Russian, "raids", 3, "3 рейда"},
+		{"missing plural form uses other", discordgo.Russian, "raids", 5, "5 рейдов"},
+		{"plural rules of the resolved locale", discordgo.French, "raids", 0, "0 raids"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if tt.n < 0 {
+				got = T(tt.locale, tt.key)
+			} else {
+				got = N(tt.locale, tt.key, tt.n)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package i18n
+
+import "github.com/bwmarrin/discordgo"
+
+// pluralCategory returns the CLDR plural category of n for cardinal numbers in the language of the locale.
+func pluralCategory(locale discordgo.Locale, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	switch language(locale) {
+	case "ru", "uk":
+		switch mod10, mod100 := n%10, n%100; {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "pl":
+		switch mod10, mod100 := n%10, n%100; {
+		case n == 1:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "fr", "pt":
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+	case "ja", "ko", "zh", "th", "vi", "id":
+		return "other"
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
@@ -0,0 +1,60 @@
+package i18n
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+var pluralCategories = []string{"zero", "one", "two", "few", "many", "other"}
+
+// parseTOML reads a locale file in TOML, nested tables join their keys with dots, so `[embed] footer = ""`
+// and `"embed.footer" = ""` are the same key. Tables of plural categories with the other form are plural messages.
+func parseTOML(data []byte) (Bundle, error) {
+	var raw map[string]any
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	b := Bundle{}
+	return b, flattenTOML(b, "", raw)
+}
+
+func flattenTOML(b Bundle, prefix string, table map[string]any) error {
+	for k, v := range table {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			b[key] = Message{"other": v}
+		case map[string]any:
+			if msg, ok := pluralMessage(v); ok {
+				b[key] = msg
+				continue
+			}
+			if err := flattenTOML(b, key, v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("key %v is neither a message nor a table", key)
+		}
+	}
+	return nil
+}
+
+func pluralMessage(table map[string]any) (Message, bool) {
+	if _, ok := table["other"]; !ok {
+		return nil, false
+	}
+	msg := Message{}
+	for k, v := range table {
+		text, ok := v.(string)
+		if !ok || !slices.Contains(pluralCategories, k) {
+			return nil, false
+		}
+		msg[k] = text
+	}
+	return msg, true
+}
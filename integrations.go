@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"bot/calendar"
+	"bot/events"
+	"bot/i18n"
+	"bot/sheets"
+	"bot/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func (c *serverCommands) events(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	opt, ok := optionMap(data.Options)["url"]
+	server, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if !ok {
+			server.EventsURL, server.EventsSecret = "", ""
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		u, err := events.ValidateURL(ctx, opt.StringValue())
+		cancel()
+		if err != nil {
+			slog.Info("event webhook url rejected", slog.String("server", i.GuildID), "error", err)
+			return refuse("events.invalid_url")
+		}
+		server.EventsURL = u.String()
+		server.EventsSecret = events.NewSecret()
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("event webhook updated", slog.String("server", i.GuildID), slog.Bool("enabled", ok))
+	if ok {
+		respond(s, i, i18n.T(i.Locale, "events.enabled", server.EventsURL, server.EventsSecret))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "events.disabled"))
+	}
+}
+
+func (c *serverCommands) statsAPI(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	enabled := optionMap(data.Options)["enabled"].BoolValue()
+	server, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		server.ApiToken = ""
+		if enabled {
+			server.ApiToken = events.NewSecret()
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("stats api updated", slog.String("server", i.GuildID), slog.Bool("enabled", enabled))
+	if enabled {
+		respond(s, i, i18n.T(i.Locale, "api.enabled",
+			statsAPIURL(c.config.PublicURL, server.ServerId),
+			grafanaURL(c.config.PublicURL, server.ServerId),
+			overlayURL(c.config.PublicURL, server.ServerId, server.ApiToken),
+			server.ApiToken,
+		))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "api.disabled"))
+	}
+}
+
+func (c *serverCommands) timeSeries(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	enabled := optionMap(data.Options)["enabled"].BoolValue()
+	server, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		server.TimeSeries = enabled
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	if !enabled {
+		if err := c.store.DeleteSeries(server.ServerId); err != nil {
+			slog.Error("error deleting time series", slog.String("server", i.GuildID), "error", err)
+		}
+	}
+	slog.Info("time series updated", slog.String("server", i.GuildID), slog.Bool("enabled", enabled))
+	if enabled {
+		respond(s, i, i18n.T(i.Locale, "series.enabled", grafanaURL(c.config.PublicURL, server.ServerId)))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "series.disabled"))
+	}
+}
+
+func (c *serverCommands) sheet(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	if c.sheets == nil {
+		respond(s, i, i18n.T(i.Locale, "sheet.unavailable"))
+		return
+	}
+	options := optionMap(data.Options)
+	opt, ok := options["spreadsheet"]
+	var title string
+	server, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if !ok {
+			server.SpreadsheetId, server.SpreadsheetTab = "", ""
+			return nil
+		}
+		id := sheets.SpreadsheetId(opt.StringValue())
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		var err error
+		title, err = c.sheets.Title(ctx, id)
+		cancel()
+		if err != nil {
+			slog.Warn("error accessing spreadsheet", slog.String("server", i.GuildID), "error", err)
+			return refuse("sheet.no_access", c.sheets.Email())
+		}
+		owner, err := c.store.Bind(storage.SpreadsheetResource(id), i.GuildID)
+		if err != nil {
+			return fmt.Errorf("binding spreadsheet: %w", err)
+		}
+		if owner != i.GuildID {
+			slog.Warn("spreadsheet is bound to another server", slog.String("server", i.GuildID), slog.String("owner", owner))
+			return refuse("sheet.taken")
+		}
+		server.SpreadsheetId = id
+		server.SpreadsheetTab = ""
+		if tab, ok := options["tab"]; ok {
+			server.SpreadsheetTab = tab.StringValue()
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("spreadsheet export updated", slog.String("server", i.GuildID), slog.Bool("enabled", ok))
+	if !ok {
+		respond(s, i, i18n.T(i.Locale, "sheet.disabled"))
+		return
+	}
+	respond(s, i, i18n.T(i.Locale, "sheet.enabled", title))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	empty, err := c.sheets.Empty(ctx, server.SpreadsheetId, server.SpreadsheetTab)
+	if err == nil && empty {
+		err = c.sheets.Append(ctx, server.SpreadsheetId, server.SpreadsheetTab, [][]any{sheetHeader})
+	}
+	if err != nil {
+		slog.Warn("error writing spreadsheet header", slog.String("server", i.GuildID), "error", err)
+	}
+}
+
+func (c *serverCommands) calendarFeed(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	options := optionMap(data.Options)
+	opt, ok := options["url"]
+	var upcoming []calendar.Event
+	_, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if !ok {
+			server.CalendarURL, server.CalendarAnnounce = "", false
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		defer cancel()
+		u, err := calendar.ValidateURL(ctx, opt.StringValue())
+		if err != nil {
+			slog.Info("calendar url rejected", slog.String("server", i.GuildID), "error", err)
+			return refuse("calendar.invalid_url")
+		}
+		evs, err := c.calendar.Events(ctx, u.String())
+		if err != nil {
+			slog.Warn("error loading calendar", slog.String("server", i.GuildID), "error", err)
+			return refuse("calendar.unavailable")
+		}
+		for _, e := range evs {
+			if e.Start.After(time.Now()) {
+				upcoming = append(upcoming, e)
+			}
+		}
+		server.CalendarURL = u.String()
+		server.CalendarAnnounce = true
+		if opt, ok := options["announce"]; ok {
+			server.CalendarAnnounce = opt.BoolValue()
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("calendar updated", slog.String("server", i.GuildID), slog.Bool("enabled", ok))
+	switch {
+	case !ok:
+		respond(s, i, i18n.T(i.Locale, "calendar.disabled"))
+	case len(upcoming) == 0:
+		respond(s, i, i18n.T(i.Locale, "calendar.enabled_empty"))
+	default:
+		respond(s, i, i18n.T(i.Locale, "calendar.enabled", discordTime(upcoming[0].Start, 'F')))
+	}
+}
+
+func (c *serverCommands) mirror(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	options := optionMap(data.Options)
+	platform := options["platform"].StringValue()
+	if !c.mirrors.available(platform) {
+		respond(s, i, i18n.T(i.Locale, "mirror.unavailable", platform))
+		return
+	}
+	opt, ok := options["target"]
+	server, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if !ok {
+			delete(server.Mirrors, platform)
+			return nil
+		}
+		target := strings.TrimSpace(opt.StringValue())
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		err := c.mirrors.check(ctx, platform, target)
+		cancel()
+		if err != nil {
+			slog.Info("mirror target rejected", slog.String("server", i.GuildID), slog.String("platform", platform), "error", err)
+			return refuse("mirror.no_access", platform)
+		}
+		owner, err := c.store.Bind(storage.MirrorResource(platform, target), i.GuildID)
+		if err != nil {
+			return fmt.Errorf("binding mirror: %w", err)
+		}
+		if owner != i.GuildID {
+			slog.Warn("mirror target is bound to another server", slog.String("server", i.GuildID), slog.String("platform", platform), slog.String("owner", owner))
+			return refuse("mirror.taken", platform)
+		}
+		if server.Mirrors == nil {
+			server.Mirrors = make(map[string]string)
+		}
+		server.Mirrors[platform] = target
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("mirror updated", slog.String("server", i.GuildID), slog.String("platform", platform), slog.Bool("enabled", ok))
+	if ok {
+		respond(s, i, i18n.T(i.Locale, "mirror.enabled", platform, server.Mirrors[platform]))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "mirror.disabled", platform))
+	}
+}
+
+func (c *serverCommands) streamer(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	if c.twitch == nil {
+		respond(s, i, i18n.T(i.Locale, "twitch.unavailable"))
+		return
+	}
+	options := optionMap(data.Options)
+	character := strings.TrimSpace(options["character"].StringValue())
+	login := ""
+	if opt, ok := options["channel"]; ok {
+		login, ok = twitchLogin(opt.StringValue())
+		if !ok {
+			respond(s, i, i18n.T(i.Locale, "twitch.invalid_channel"))
+			return
+		}
+	}
+	key := strings.ToLower(character)
+	_, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if login == "" {
+			delete(server.Streamers, key)
+			return nil
+		}
+		if server.Streamers == nil {
+			server.Streamers = make(map[string]string)
+		}
+		server.Streamers[key] = login
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("streamer updated", slog.String("server", i.GuildID), slog.String("character", key), slog.String("channel", login))
+	if login != "" {
+		respond(s, i, i18n.T(i.Locale, "twitch.added", character, login))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "twitch.removed", character))
+	}
+}
+
+func (c *serverCommands) scheduledEvents(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	enabled := optionMap(data.Options)["enabled"].BoolValue()
+	_, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if enabled && server.CalendarURL == "" {
+			return refuse("schedule.no_calendar")
+		}
+		server.ScheduledEvents = enabled
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("scheduled events updated", slog.String("server", i.GuildID), slog.Bool("enabled", enabled))
+	if enabled {
+		respond(s, i, i18n.T(i.Locale, "schedule.enabled"))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "schedule.disabled"))
+	}
+}
+
+func (c *serverCommands) linkReplies(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	if !c.config.LinkReplies {
+		respond(s, i, i18n.T(i.Locale, "links.unavailable"))
+		return
+	}
+	options := optionMap(data.Options)
+	channelId := options["channel"].ChannelValue(s).ID
+	enabled := options["enabled"].BoolValue()
+	_, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		server.LinkChannels = slices.DeleteFunc(server.LinkChannels, func(id string) bool { return id == channelId })
+		if enabled {
+			server.LinkChannels = append(server.LinkChannels, channelId)
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("link replies updated", slog.String("server", i.GuildID), slog.String("channel", channelId), slog.Bool("enabled", enabled))
+	if enabled {
+		respond(s, i, i18n.T(i.Locale, "links.enabled", channelId))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "links.disabled", channelId))
+	}
+}
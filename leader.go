@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"syscall"
+	"time"
+)
+
+const leaderRetryInterval = 5 * time.Second
+
+// acquireLeadership blocks until this process holds an exclusive lock on the file at path, so only one of
+// several instances sharing the file runs the watchers. The lock is released by the kernel when the process
+// dies, which lets a standby take over. The returned file must stay open for as long as leadership is held.
+func acquireLeadership(ctx context.Context, path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening leader lock file: %w", err)
+	}
+
+	logged := false
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, fmt.Errorf("error locking leader lock file: %w", err)
+		}
+		if !logged {
+			slog.Info("another instance is the leader, waiting in standby", slog.String("lock_file", path))
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(leaderRetryInterval):
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "%v %d\n", hostname, os.Getpid())
+	}
+	slog.Info("acquired leadership", slog.String("lock_file", path))
+	return f, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// linkReplyLimit is how many links a server gets answered per linkReplyWindow.
+	linkReplyLimit  = 5
+	linkReplyWindow = time.Minute
+	// linkDedupe is how long the same report is not answered again in a channel.
+	linkDedupe = 30 * time.Minute
+	// linkRetry is how long a report that got no reply, because it failed to load or had no fights yet, is not
+	// looked up again in a channel.
+	linkRetry = 2 * time.Minute
+)
+
+var reportLinkPattern = regexp.MustCompile(`https?://(?:www\.)?(warcraftlogs|fflogs|esologs)\.com/reports/([a-zA-Z0-9]{16})`)
+
+// reportLink returns the site and the code of the first report link in the message.
+func reportLink(content string) (warcraftlogs.Site, string, bool) {
+	m := reportLinkPattern.FindStringSubmatch(content)
+	if m == nil {
+		return warcraftlogs.Site{}, "", false
+	}
+	site, ok := warcraftlogs.SiteById(m[1])
+	return site, m[2], ok
+}
+
+// linkReplies limits the report lookups per server and skips reports looked up recently in the channel.
+type linkReplies struct {
+	mu      sync.Mutex
+	lookups map[string][]time.Time
+	// blocked holds until when a report is not looked up again in a channel
+	blocked map[string]time.Time
+}
+
+func newLinkReplies() *linkReplies {
+	return &linkReplies{lookups: make(map[string][]time.Time), blocked: make(map[string]time.Time)}
+}
+
+// allow takes a lookup of the server limit for the report unless the limit is used up or the report was looked up
+// recently in the channel. Every lookup counts, whether the report loads or not.
+func (l *linkReplies) allow(serverId, channelId, code string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for key, until := range l.blocked {
+		if now.After(until) {
+			delete(l.blocked, key)
+		}
+	}
+	key := channelId + "/" + code
+	if _, ok := l.blocked[key]; ok {
+		return false
+	}
+	recent := l.lookups[serverId][:0]
+	for _, at := range l.lookups[serverId] {
+		if now.Sub(at) < linkReplyWindow {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= linkReplyLimit {
+		l.lookups[serverId] = recent
+		return false
+	}
+	l.lookups[serverId] = append(recent, now)
+	l.blocked[key] = now.Add(linkRetry)
+	return true
+}
+
+// answered keeps the report from being answered again in the channel for linkDedupe.
+func (l *linkReplies) answered(channelId, code string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.blocked[channelId+"/"+code] = time.Now().Add(linkDedupe)
+}
+
+// constructLinkReplyEmbed is the report embed without the refresh footer, so the reply is never taken for a live message.
+func constructLinkReplyEmbed(stats watcher.StatsEvent) *discordgo.MessageEmbed {
+	embed := constructEmbed(stats, embedModeOrDefault(stats.Server.EmbedMode), nil, nil, "")
+	embed.Footer = nil
+	embed.Timestamp = ""
+	return embed
+}
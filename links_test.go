@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportLink(t *testing.T) {
+	tests := []struct {
+		content string
+		site    string
+		code    string
+		ok      bool
+	}{
+		{"https://www.warcraftlogs.com/reports/aBcD1234eFgH5678", "warcraftlogs", "aBcD1234eFgH5678", true},
+		{"look <https://fflogs.com/reports/aBcD1234eFgH5678#fight=3>", "fflogs", "aBcD1234eFgH5678", true},
+		{"https://www.warcraftlogs.com/reports/short", "", "", false},
+		{"https://example.com/reports/aBcD1234eFgH5678", "", "", false},
+	}
+	for _, tt := range tests {
+		site, code, ok := reportLink(tt.content)
+		if ok != tt.ok || code != tt.code || (ok && site.Id != tt.site) {
+			t.Errorf("reportLink(%q) = %v, %v, %v", tt.content, site.Id, code, ok)
+		}
+	}
+}
+
+func TestLinkRepliesAllow(t *testing.T) {
+	type lookup struct {
+		server, channel, code string
+		want                  bool
+	}
+	tests := []struct {
+		name    string
+		lookups []lookup
+	}{
+		{
+			name: "same report in the channel is looked up once",
+			lookups: []lookup{
+				{"s", "c", "r1", true},
+				{"s", "c", "r1", false},
+				{"s", "c2", "r1", true},
+			},
+		},
+		{
+			name: "server limit",
+			lookups: []lookup{
+				{"s", "c", "r1", true},
+				{"s", "c", "r2", true},
+				{"s", "c", "r3", true},
+				{"s", "c", "r4", true},
+				{"s", "c", "r5", true},
+				{"s", "c", "r6", false},
+				{"s2", "c", "r6", true},
+			},
+		},
+		{
+			name: "refused lookups don't use the limit",
+			lookups: []lookup{
+				{"s", "c", "r1", true},
+				{"s", "c", "r1", false},
+				{"s", "c", "r1", false},
+				{"s", "c", "r1", false},
+				{"s", "c", "r1", false},
+				{"s", "c", "r2", true},
+				{"s", "c", "r3", true},
+				{"s", "c", "r4", true},
+				{"s", "c", "r5", true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLinkReplies()
+			for i, lk := range tt.lookups {
+				if got := l.allow(lk.server, lk.channel, lk.code); got != lk.want {
+					t.Errorf("lookup %v of %v in %v/%v = %v, want %v", i, lk.code, lk.server, lk.channel, got, lk.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkRepliesExpiry(t *testing.T) {
+	l := newLinkReplies()
+	if !l.allow("s", "c", "r1") {
+		t.Fatal("first lookup refused")
+	}
+	// a lookup that got no reply is retried after linkRetry, an answered one only after linkDedupe
+	l.blocked["c/r1"] = time.Now().Add(-time.Second)
+	l.lookups["s"] = []time.Time{time.Now().Add(-linkReplyWindow)}
+	if !l.allow("s", "c", "r1") {
+		t.Fatal("lookup refused after the block and the window passed")
+	}
+	l.answered("c", "r1")
+	if until := l.blocked["c/r1"]; time.Until(until) <= linkRetry {
+		t.Fatalf("answered report blocked until %v, want about %v from now", until, linkDedupe)
+	}
+}
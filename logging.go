@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+type LogConfig struct {
+	Level              string `envconfig:"LOG_LEVEL" yaml:"log_level" toml:"log_level"`
+	Format             string `envconfig:"LOG_FORMAT" yaml:"log_format" toml:"log_format"`
+	Sampling           bool   `envconfig:"LOG_SAMPLING" yaml:"log_sampling" toml:"log_sampling"`
+	SamplingInitial    int    `envconfig:"LOG_SAMPLING_INITIAL" yaml:"log_sampling_initial" toml:"log_sampling_initial"`
+	SamplingThereafter int    `envconfig:"LOG_SAMPLING_THEREAFTER" yaml:"log_sampling_thereafter" toml:"log_sampling_thereafter"`
+}
+
+func newLogger(cfg LogConfig) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, level, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	var zcfg zap.Config
+	switch cfg.Format {
+	case "json":
+		zcfg = zap.NewProductionConfig()
+	case "console":
+		zcfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, level, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	zcfg.Level = level
+	zcfg.Sampling = nil
+	if cfg.Sampling {
+		zcfg.Sampling = &zap.SamplingConfig{
+			Initial:    cfg.SamplingInitial,
+			Thereafter: cfg.SamplingThereafter,
+		}
+	}
+
+	logger, err := zcfg.Build()
+	return logger, level, err
+}
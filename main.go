@@ -1,238 +1,849 @@
 package main
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
-	"unicode/utf8"
 
+	"bot/battlenet"
+	"bot/calendar"
+	"bot/errreport"
+	"bot/events"
+	"bot/features"
+	"bot/i18n"
+	"bot/mechanics"
+	"bot/metrics"
+	"bot/notify"
+	"bot/outbox"
+	"bot/raiderio"
+	"bot/sheets"
 	"bot/storage"
+	"bot/twitch"
+	"bot/version"
 	"bot/warcraftlogs"
 	"bot/watcher"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/jellydator/ttlcache/v3"
-	"github.com/kelseyhightower/envconfig"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	bolt "go.etcd.io/bbolt"
-	"go.uber.org/zap"
 	"go.uber.org/zap/exp/zapslog"
 )
 
-type Config struct {
-	DiscordBotToken string `envconfig:"DISCORD_BOT_TOKEN" required:"true"`
-	WLClientId      string `envconfig:"WL_CLIENT_ID" required:"true"`
-	WLClientSecret  string `envconfig:"WL_CLIENT_SECRET" required:"true"`
-}
-
 func main() {
-	var config Config
-	envconfig.MustProcess("", &config)
+	cfgPath := configPath()
+	config, err := loadConfig(cfgPath)
+	if err != nil {
+		panic(err)
+	}
 
-	zlogger, _ := zap.NewDevelopment()
+	zlogger, logLevel, err := newLogger(config.LogConfig)
+	if err != nil {
+		panic(err)
+	}
 	defer zlogger.Sync()
-	slogger := slog.New(zapslog.NewHandler(zlogger.Core()))
+	slogger := slog.New(zapslog.NewHandler(zlogger.Core())).With(slog.String("version", version.Version))
 	slog.SetDefault(slogger)
+	slog.Info("starting bot", slog.String("build", version.String()))
+
+	if config.ReportErrors {
+		errreport.Register(errreport.LogReporter{})
+	}
 
-	db, err := bolt.Open("./store.db", 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if config.LocalesDir != "" {
+		if err := i18n.LoadDir(config.LocalesDir); err != nil {
+			slog.Error("error loading locales", slog.String("dir", config.LocalesDir), "error", err)
+		}
+		refreshLanguageChoices()
+	}
+	if config.MechanicsFile != "" {
+		if err := mechanics.Load(config.MechanicsFile); err != nil {
+			slog.Error("error loading mechanics", slog.String("file", config.MechanicsFile), "error", err)
+		}
+	}
+
+	if config.LeaderLockFile != "" {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		lock, err := acquireLeadership(ctx, config.LeaderLockFile)
+		cancel()
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		defer lock.Close()
+	}
+
+	db, err := bolt.Open(config.DBPath, 0o600, &bolt.Options{Timeout: 5 * time.Second})
 	if err != nil {
 		panic(err)
 	}
-	defer db.Close()
 	storage.MustInitDB(db)
 	store := storage.New(db)
 
-	wlClient, err := warcraftlogs.NewClient(config.WLClientId, config.WLClientSecret)
+	wlClient, err := warcraftlogs.NewClient(warcraftlogs.Warcraft, config.WLClientId, config.WLClientSecret)
 	if err != nil {
 		panic(err)
 	}
-	w := watcher.New(wlClient)
+	wlClients := map[string]*warcraftlogs.Client{warcraftlogs.Warcraft.Id: wlClient}
+	for site, creds := range map[warcraftlogs.Site][2]string{
+		warcraftlogs.FFLogs:  {config.FFLogsClientId, config.FFLogsClientSecret},
+		warcraftlogs.ESOLogs: {config.ESOLogsClientId, config.ESOLogsClientSecret},
+	} {
+		if creds[0] == "" {
+			continue
+		}
+		client, err := warcraftlogs.NewClient(site, creds[0], creds[1])
+		if err != nil {
+			panic(err)
+		}
+		wlClients[site.Id] = client
+	}
+	var chats []notify.Chat
+	if config.SlackBotToken != "" {
+		chats = append(chats, notify.NewSlack(config.SlackBotToken))
+	}
+	if config.TelegramBotToken != "" {
+		chats = append(chats, notify.NewTelegram(config.TelegramBotToken))
+	}
+	chatMirrors := newMirrors(chats...)
+	calClient := calendar.NewClient()
+	w := watcher.New(wlClients, calClient)
+	var bnetClient *battlenet.Client
+	if config.BattleNetClientId != "" {
+		bnetClient = battlenet.NewClient(config.BattleNetClientId, config.BattleNetClientSecret)
+	}
+	var twitchClient *twitch.Client
+	if config.TwitchClientId != "" {
+		twitchClient = twitch.NewClient(config.TwitchClientId, config.TwitchClientSecret)
+	}
+	guilds := newGuildLookup(wlClient, raiderio.NewClient(), bnetClient)
+	login := newBattleNetLogin(store, guilds, config.PublicURL)
+	var sheetsClient *sheets.Client
+	if config.GoogleCredentialsFile != "" {
+		sheetsClient, err = sheets.NewClient(config.GoogleCredentialsFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+	watcher.SetDefaultPollInterval(config.DefaultPollInterval)
 
 	token := "Bot " + config.DiscordBotToken
-	dg, err := discordgo.New(token)
+	sessions, err := newSessions(token, config.ShardConfig)
 	if err != nil {
 		panic(err)
 	}
+	// any session can be used for REST calls, only gateway events are bound to a shard
+	dg := sessions[0]
+	if config.LinkReplies {
+		// answering report links needs the content of messages, a privileged intent
+		for _, s := range sessions {
+			s.Identify.Intents |= discordgo.IntentsMessageContent
+		}
+	}
+	wlClient.OnOutage(config.WCLAlertThreshold,
+		func(err error) {
+			slog.Error("warcraftlogs api is failing, alerting operator", "error", err)
+			alertOperator(dg, config.OwnerId, config.OpsChannelId,
+				i18n.T(i18n.Fallback, "alert.wcl_outage", config.WCLAlertThreshold, err))
+		},
+		func() {
+			slog.Info("warcraftlogs api recovered")
+			alertOperator(dg, config.OwnerId, config.OpsChannelId, i18n.T(i18n.Fallback, "alert.wcl_recovered"))
+		},
+	)
+	addHandler := func(handler any) {
+		for _, s := range sessions {
+			s.AddHandler(handler)
+		}
+	}
 
-	messageCache := ttlcache.New[string, string](
-		ttlcache.WithTTL[string, string](12 * time.Hour),
+	messageCache := ttlcache.New[string, postedMessage](
+		ttlcache.WithTTL[string, postedMessage](12 * time.Hour),
 	)
 	go messageCache.Start()
 
-	dg.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
-		slog.Info("bot is online")
+	statsCache := ttlcache.New[string, watcher.StatsEvent](
+		ttlcache.WithTTL[string, watcher.StatsEvent](12 * time.Hour),
+	)
+	go statsCache.Start()
+
+	modeCache := ttlcache.New[string, string](
+		ttlcache.WithTTL[string, string](12 * time.Hour),
+	)
+	go modeCache.Start()
+
+	rankCache := ttlcache.New[string, string](
+		ttlcache.WithTTL[string, string](12 * time.Hour),
+	)
+	go rankCache.Start()
+
+	feedbackCache := ttlcache.New[string, struct{}](
+		ttlcache.WithTTL[string, struct{}](feedbackCooldown),
+	)
+	go feedbackCache.Start()
+
+	// usage is counted in memory and stored in batches
+	usageCtx, stopUsage := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-usageCtx.Done():
+				return
+			case <-ticker.C:
+				if err := store.FlushUsage(); err != nil {
+					slog.Error("error storing usage", "error", err)
+				}
+			}
+		}
+	}()
+
+	queue := outbox.New(config.DeliveryRate)
+	queueCtx, stopQueue := context.WithCancel(context.Background())
+	go queue.Run(queueCtx)
+
+	// a new locale has to be offered in the language choices of set-config, which every server has registered
+	i18n.OnLocalesChanged(func() {
+		refreshLanguageChoices()
+		for _, sess := range sessions {
+			sess.State.RLock()
+			guilds := slices.Clone(sess.State.Guilds)
+			sess.State.RUnlock()
+			for _, g := range guilds {
+				queue.Enqueue("commands:"+g.ID, func() { registerCommands(sess, g, guildCommands(g.ID, config.OwnerGuildId)) })
+			}
+		}
 	})
+	localesCtx, stopLocales := context.WithCancel(context.Background())
+	if config.LocalesDir != "" {
+		go i18n.WatchDir(localesCtx, config.LocalesDir, 30*time.Second)
+	}
 
-	dg.AddHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
-		slog.Info("bot is connected to server", slog.String("server", g.Guild.ID), slog.String("server_name", g.Guild.Name))
-		registerCommands(s, g.Guild)
-		srv, err := store.ReadServer(g.Guild.ID)
-		if err != nil {
-			slog.Error("error loading server configuration", slog.String("server", g.Guild.ID), "error", err)
+	commands := &serverCommands{
+		config:   config,
+		store:    store,
+		w:        w,
+		owner:    &ownerCommands{ownerId: config.OwnerId, store: store, w: w, wlClient: wlClient, queue: queue, sessions: sessions},
+		calendar: calClient,
+		mirrors:  chatMirrors,
+		sheets:   sheetsClient,
+		twitch:   twitchClient,
+		guilds:   guilds,
+		login:    login,
+		feedback: feedbackCache,
+	}
+
+	// startWatcher restores the live messages of the server from the history of its channel and raid threads,
+	// so reports already posted before a restart are edited instead of reposted, and starts the watcher if it is
+	// not running yet.
+	startWatcher := func(s *discordgo.Session, srv storage.Server) {
+		if _, isWatched := w.Status(srv.ServerId); isWatched {
 			return
 		}
-		if srv != nil {
-			msgs, err := s.ChannelMessages(srv.ChannelId, 100, "", "", "")
+		var msgs []*discordgo.Message
+		// forum channels have no messages of their own, reports are only posted to their raid threads
+		if !isForum(s, srv.ChannelId) {
+			var err error
+			msgs, err = s.ChannelMessages(srv.ChannelId, 100, "", "", "")
 			if err != nil {
-				slog.Error("error loading message history", slog.String("server", g.Guild.ID), slog.String("channel", srv.ChannelId), "error", err)
+				slog.Error("error loading message history", slog.String("server", srv.ServerId), slog.String("channel", srv.ChannelId), "error", err)
 			}
-			for _, msg := range msgs {
-				if msg.Author.ID != s.State.User.ID {
-					continue
-				}
-				lastDate := msg.Timestamp
-				if msg.EditedTimestamp != nil {
-					lastDate = *msg.EditedTimestamp
-				}
-				if time.Since(lastDate) > 12*time.Hour {
-					continue
-				}
+		}
+		threadIds, err := store.RaidThreads(srv.ServerId, srv.ChannelId)
+		if err != nil {
+			slog.Error("error reading raid threads", slog.String("server", srv.ServerId), "error", err)
+		}
+		for _, threadId := range threadIds {
+			threadMsgs, err := s.ChannelMessages(threadId, 100, "", "", "")
+			if err != nil {
+				slog.Error("error loading message history", slog.String("server", srv.ServerId), slog.String("thread", threadId), "error", err)
+				continue
+			}
+			msgs = append(msgs, threadMsgs...)
+		}
+		for _, msg := range msgs {
+			if !postedBy(s, srv, msg) {
+				continue
+			}
+			lastDate := msg.Timestamp
+			if msg.EditedTimestamp != nil {
+				lastDate = *msg.EditedTimestamp
+			}
+			if time.Since(lastDate) > 12*time.Hour {
+				continue
+			}
+			url, ok := reportURL(msg)
+			if !ok {
+				continue
+			}
+			idx := strings.LastIndex(url, "/")
+			reportCode := url[idx+1:]
 
-				url := msg.Embeds[0].URL
-				idx := strings.LastIndex(url, "/")
-				reportCode := url[idx+1:]
+			key := srv.ServerId + srv.ChannelId + reportCode
+			messageCache.Set(key, newPostedMessage(msg), ttlcache.DefaultTTL)
+		}
+		slog.Info("starting watcher", slog.String("server", srv.ServerId))
+		w.Watch(srv)
+	}
 
-				key := srv.ServerId + srv.ChannelId + reportCode
-				messageCache.Set(key, msg.ID, ttlcache.DefaultTTL)
+	addHandler(func(s *discordgo.Session, r *discordgo.Ready) {
+		defer errreport.Recover(context.Background(), map[string]string{"component": "discord", "handler": "ready"})
+		slog.Info("bot is online", slog.Int("shard", s.ShardID))
+
+		// only servers the bot is still a member of are watched, it may have been removed from some while it was
+		// offline and no GuildDelete is sent for them
+		servers, err := store.ListServers()
+		if err != nil {
+			slog.Error("error loading server configurations", "error", err)
+			return
+		}
+		joined := make(map[string]bool, len(r.Guilds))
+		for _, g := range r.Guilds {
+			joined[g.ID] = true
+		}
+		for _, srv := range servers {
+			if guildShard(srv.ServerId, s.ShardCount) != s.ShardID {
+				continue
 			}
-			slog.Info("starting watcher", slog.String("server", g.Guild.ID))
-			w.Watch(*srv)
+			if joined[srv.ServerId] {
+				startWatcher(s, srv)
+				continue
+			}
+			slog.Warn("bot is not a member of configured server, stopping watcher", slog.String("server", srv.ServerId))
+			w.Unwatch(srv.ServerId)
 		}
 	})
 
-	dg.AddHandler(func(s *discordgo.Session, g *discordgo.GuildDelete) {
-		slog.Info("bot is disconnected from server", slog.String("server", g.Guild.ID))
-		store.DeleteServer(g.Guild.ID)
-		w.Unwatch(g.Guild.ID)
+	addHandler(func(s *discordgo.Session, g *discordgo.GuildCreate) {
+		defer errreport.Recover(context.Background(), map[string]string{"component": "discord", "handler": "guild_create"})
+		slog.Info("bot is connected to server", slog.String("server", g.Guild.ID), slog.String("server_name", g.Guild.Name))
+		registerCommands(s, g.Guild, guildCommands(g.Guild.ID, config.OwnerGuildId))
+		srv, err := store.ReadServer(g.Guild.ID)
+		if err != nil {
+			slog.Error("error loading server configuration", slog.String("server", g.Guild.ID), "error", err)
+			return
+		}
+		if srv == nil {
+			return
+		}
+		// servers configured before the locale was stored post in the server language
+		if srv.Locale == "" && g.Guild.PreferredLocale != "" {
+			srv.Locale = g.Guild.PreferredLocale
+			if err := store.SaveServer(*srv); err != nil {
+				slog.Error("error saving server locale", slog.String("server", srv.ServerId), "error", err)
+			}
+		}
+		startWatcher(s, *srv)
 	})
 
-	dg.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if i.Type != discordgo.InteractionApplicationCommand {
+	addHandler(func(s *discordgo.Session, g *discordgo.GuildDelete) {
+		defer errreport.Recover(context.Background(), map[string]string{"component": "discord", "handler": "guild_delete"})
+		// an outage of the server is sent as a GuildDelete as well, only a removal of the bot deletes its data
+		if g.Unavailable {
+			slog.Warn("server is unavailable", slog.String("server", g.Guild.ID))
 			return
 		}
-		data := i.ApplicationCommandData()
+		slog.Info("bot is disconnected from server", slog.String("server", g.Guild.ID))
+		if err := store.DeleteServer(g.Guild.ID); err != nil {
+			slog.Error("error deleting server configuration", slog.String("server", g.Guild.ID), "error", err)
+		}
+		w.Unwatch(g.Guild.ID)
+	})
 
-		switch data.Name {
-		case "set-config":
-			channelId := data.Options[0].ChannelValue(s).ID
-			wlGuildId := int64(data.Options[1].Value.(float64))
-			wipeCutoff := int64(data.Options[2].Value.(float64))
-			server := storage.Server{
-				ServerId:   i.GuildID,
-				ChannelId:  channelId,
-				WlGuildId:  wlGuildId,
-				WipeCutoff: wipeCutoff,
+	if config.LinkReplies {
+		links := newLinkReplies()
+		addHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+			defer errreport.Recover(context.Background(), map[string]string{"component": "discord", "handler": "message_create"})
+			if m.GuildID == "" || m.Author == nil || m.Author.Bot {
+				return
+			}
+			site, code, ok := reportLink(m.Content)
+			if !ok {
+				return
 			}
-			err := store.SaveServer(server)
+			server, err := store.ReadServer(m.GuildID)
 			if err != nil {
-				slog.Error("error saving configuration", slog.String("server", i.GuildID), "error", err)
-				switch i.Locale {
-				case discordgo.Russian:
-					respond(s, i, "❌ Ошибка, попробуйте еще раз")
-				default:
-					respond(s, i, "❌ Error, try again")
-				}
+				slog.Error("error reading configuration", slog.String("server", m.GuildID), "error", err)
 				return
 			}
-			slog.Info("stopping watcher", "server", server.ServerId)
-			w.Unwatch(server.ServerId)
-			slog.Info("starting watcher", "server", server.ServerId)
-			w.Watch(server)
-			slog.Info("bot is configured", slog.String("server", i.GuildID), slog.String("channelId", channelId), slog.Int64("wlGuildId", wlGuildId))
-			switch i.Locale {
-			case discordgo.Russian:
-				respond(s, i, "✅ Бот настроен")
-			default:
-				respond(s, i, "✅ Bot is configured")
+			if server == nil || !slices.Contains(server.LinkChannels, m.ChannelID) || !links.allow(m.GuildID, m.ChannelID, code) {
+				return
+			}
+			server.Site = site.Id
+			if server.Locale == "" {
+				server.Locale = string(preferredLocale(sessions, server.ServerId))
 			}
-		case "get-config":
-			server, err := store.ReadServer(i.GuildID)
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+			se, err := w.ReportStats(ctx, *server, code)
 			if err != nil {
-				slog.Error("error reading configuration", slog.String("server", i.GuildID), "error", err)
-				switch i.Locale {
-				case discordgo.Russian:
-					respond(s, i, "❌ Ошибка, попробуйте еще раз")
-				default:
-					respond(s, i, "❌ Error, try again")
-				}
+				slog.Warn("error loading linked report", slog.String("server", m.GuildID), slog.String("report", code), "error", err)
+				return
+			}
+			// a log without boss fights yet is answered once it has some
+			if se.Kills+se.Wipes == 0 {
 				return
 			}
-			if server == nil {
-				respond(s, i, "⚠️ Бот не настроен")
+			reference := m.Reference()
+			queue.Enqueue("link:"+m.ChannelID+":"+code, func() {
+				_, err := dg.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+					Embeds:          []*discordgo.MessageEmbed{constructLinkReplyEmbed(se)},
+					Reference:       reference,
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				})
+				if err != nil {
+					slog.Error("error replying to report link", slog.String("server", m.GuildID), slog.String("channel", m.ChannelID), "error", err)
+				}
+			})
+			links.answered(m.ChannelID, code)
+		})
+	}
+
+	var shuttingDown atomic.Bool
+	rejectIfShuttingDown := func(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+		if !shuttingDown.Load() {
+			return false
+		}
+		respond(s, i, i18n.T(i.Locale, "error.restarting"))
+		return true
+	}
+
+	addHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		defer errreport.Recover(context.Background(), map[string]string{"component": "discord", "handler": "interaction"})
+		if i.Type != discordgo.InteractionMessageComponent {
+			return
+		}
+		if rejectIfShuttingDown(s, i) {
+			return
+		}
+		data := i.MessageComponentData()
+
+		switch {
+		case strings.HasPrefix(data.CustomID, analyzeMenuPrefix):
+			handleAnalyze(s, i, wlClient, store)
+		case data.CustomID == compactModeButtonId || data.CustomID == detailedModeButtonId:
+			mode := strings.TrimPrefix(data.CustomID, "embed-mode:")
+			item := statsCache.Get(i.Message.ID)
+			if item == nil {
+				respond(s, i, i18n.T(i.Locale, "error.report_not_loaded"))
 				return
 			}
-			switch i.Locale {
-			case discordgo.Russian:
-				respond(s, i, fmt.Sprintf(
-					"💡 Канал для уведомлений: <#%v>\n💡 Идентификатор гильдии на warcraftlogs.com: %v\n💡 Wipe cutoff: %v",
-					server.ChannelId, server.WlGuildId, server.WipeCutoff),
-				)
-			default:
-				respond(s, i, fmt.Sprintf(
-					"💡 Channel for notifications: <#%v>\n💡 Guild id from warcraftlogs.com: %v\n💡 Wipe cutoff: %v",
-					server.ChannelId, server.WlGuildId, server.WipeCutoff),
-				)
+			modeCache.Set(i.Message.ID, mode, ttlcache.DefaultTTL)
+			rank := ""
+			if cached := rankCache.Get(i.Message.ID); cached != nil {
+				rank = cached.Value()
 			}
-		default:
-			slog.Warn("unknown command, should remove it", slog.String("server", i.GuildID), slog.String("command", data.Name))
-			switch i.Locale {
-			case discordgo.Russian:
-				respond(s, i, "⚠️ Неизвестная команда")
-			default:
-				respond(s, i, "⚠️ Unknown command")
+			msg := constructReportMessage(item.Value(), mode, mentionClaims(store, item.Value().Server), cachedStreams(twitchClient, item.Value()), rank)
+			err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseUpdateMessage,
+				Data: &discordgo.InteractionResponseData{
+					Embeds:          msg.embeds,
+					Components:      msg.components,
+					AllowedMentions: &discordgo.MessageAllowedMentions{},
+				},
+			})
+			if err != nil {
+				slog.Error("error switching embed mode", slog.String("server", i.GuildID), slog.String("message", i.Message.ID), "error", err)
 			}
-			removeCommand(s, i.GuildID, data)
 		}
 	})
 
-	w.OnUpdate(func(se watcher.StatsEvent) {
-		key := makeKey(se)
-		embed := constructEmbed(se)
+	addHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		defer errreport.Recover(context.Background(), map[string]string{"component": "discord", "handler": "interaction"})
+		if i.Type != discordgo.InteractionApplicationCommand {
+			return
+		}
+		if rejectIfShuttingDown(s, i) {
+			return
+		}
+		data := i.ApplicationCommandData()
+		store.RecordCommand(i.GuildID, data.Name)
 
-		item := messageCache.Get(key)
+		commands.handle(s, i, data)
+	})
 
-		if item != nil {
-			_, err := dg.ChannelMessageEditComplex(&discordgo.MessageEdit{
-				ID:      item.Value(),
-				Channel: se.Server.ChannelId,
-				Embeds:  &[]*discordgo.MessageEmbed{embed},
-			})
+	notifiers := []notify.Notifier{
+		&discordNotifier{
+			dg:       dg,
+			store:    store,
+			twitch:   twitchClient,
+			guilds:   guilds,
+			failures: errreport.NewTracker(3),
+			messages: messageCache,
+			stats:    statsCache,
+			modes:    modeCache,
+			ranks:    rankCache,
+		},
+		chatMirrors,
+	}
+
+	latest := newLatestStats()
+
+	dispatcher := events.NewDispatcher()
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	go dispatcher.Run(dispatcherCtx)
+
+	w.OnAnnounce(func(ae watcher.RaidAnnounceEvent) {
+		first, err := store.MarkAnnounced(ae.Server.ServerId, ae.UID, ae.Start)
+		if err != nil {
+			slog.Error("error recording raid announcement", slog.String("server", ae.Server.ServerId), slog.String("event", ae.UID), "error", err)
+			return
+		}
+		if !first {
+			return
+		}
+		if ae.Server.Locale == "" {
+			ae.Server.Locale = string(preferredLocale(sessions, ae.Server.ServerId))
+		}
+		queue.Enqueue("announce:"+ae.Server.ServerId+":"+strconv.FormatInt(ae.Start.Unix(), 10), func() {
+			embed := constructAnnounceEmbed(ae)
+			var err error
+			if isForum(dg, ae.Server.ChannelId) {
+				// a forum channel gets a post for the announcement
+				start := &discordgo.ThreadStart{Name: truncate(embed.Title, 100), AutoArchiveDuration: raidThreadArchiveAfter}
+				_, err = dg.ForumThreadStartComplex(ae.Server.ChannelId, start, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}})
+			} else {
+				_, err = postMessage(dg, ae.Server, "", reportMessage{embeds: []*discordgo.MessageEmbed{embed}})
+			}
+			metrics.DiscordMessages.WithLabelValues("send", metrics.Result(err)).Inc()
 			if err != nil {
-				slog.Error("error updating message", slog.String("server", se.Server.ServerId), slog.String("channel", se.Server.ChannelId), "error", err)
+				slog.Error("error sending raid announcement", slog.String("server", ae.Server.ServerId), slog.String("channel", ae.Server.ChannelId), "error", err)
 			}
-			return
+		})
+	})
+
+	w.OnSchedule(func(se watcher.ScheduleEvent) {
+		if se.Server.Locale == "" {
+			se.Server.Locale = string(preferredLocale(sessions, se.Server.ServerId))
 		}
+		queue.Enqueue("schedule:"+se.Server.ServerId, func() { syncSchedule(dg, store, se) })
+	})
 
-		msgOut, err := dg.ChannelMessageSendComplex(se.Server.ChannelId, &discordgo.MessageSend{
-			Embeds: []*discordgo.MessageEmbed{embed},
-		})
-		if err != nil {
-			slog.Error("error sending message", slog.String("server", se.Server.ServerId), slog.String("channel", se.Server.ChannelId), "error", err)
-			return
+	w.OnUpdate(func(se watcher.StatsEvent) {
+		if se.Server.Locale == "" {
+			se.Server.Locale = string(preferredLocale(sessions, se.Server.ServerId))
+		}
+		store.RecordUpdate(se.Server.ServerId)
+		latest.set(se)
+		if se.Server.TimeSeries {
+			if err := store.AddPoint(se.Server.ServerId, newPoint(se)); err != nil {
+				slog.Error("error recording time series", slog.String("server", se.Server.ServerId), "error", err)
+			}
+		}
+		dispatcher.Handle(se, eventTargets(config, se.Server))
+		key := makeKey(se)
+		for _, n := range notifiers {
+			queue.Enqueue(n.Name()+":"+key, func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				if err := n.Notify(ctx, se); err != nil {
+					slog.Error("error delivering report update", slog.String("server", se.Server.ServerId), slog.String("notifier", n.Name()), slog.String("report", se.ReportId), "error", err)
+				}
+			})
+		}
+		if se.Ended {
+			// the summary loads from Raider.IO, Battle.net and the log site, which must not hold up the poll loop
+			go func() {
+				if featureEnabled(store, se.Server.ServerId, features.ReportSummary) {
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+					extras := guilds.summaryExtras(ctx, se)
+					cancel()
+					if config.MechanicsFile != "" && featureEnabled(store, se.Server.ServerId, features.MechanicsAnalysis) {
+						ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+						client, err := w.Client(se.Server)
+						if err == nil {
+							extras.Offenders, err = mechanicOffenders(ctx, client, se)
+						}
+						cancel()
+						if err != nil {
+							slog.Warn("error analysing mechanics", slog.String("server", se.Server.ServerId), slog.String("report", se.ReportId), "error", err)
+						}
+					}
+					queue.Enqueue("summary:"+key, func() {
+						threadId, err := store.RaidThread(se.Server.ServerId, se.Server.ChannelId, se.ReportId)
+						if err != nil {
+							slog.Error("error reading raid thread", slog.String("server", se.Server.ServerId), "error", err)
+						}
+						sendSummary(dg, store, se, threadId, mentionClaims(store, se.Server), extras)
+					})
+				}
+				// archived after the summary, which is posted into the thread
+				if se.Server.RaidThreads {
+					queue.Enqueue("archive:"+key, func() {
+						threadId, err := store.RaidThread(se.Server.ServerId, se.Server.ChannelId, se.ReportId)
+						if err != nil {
+							slog.Error("error reading raid thread", slog.String("server", se.Server.ServerId), "error", err)
+							return
+						}
+						if threadId == "" {
+							return
+						}
+						if err := archiveRaidThread(dg, threadId); err != nil {
+							slog.Error("error archiving raid thread", slog.String("server", se.Server.ServerId), slog.String("thread", threadId), "error", err)
+							return
+						}
+						if err := store.DeleteRaidThread(se.Server.ServerId, se.Server.ChannelId, se.ReportId); err != nil {
+							slog.Error("error deleting raid thread", slog.String("server", se.Server.ServerId), slog.String("thread", threadId), "error", err)
+						}
+					})
+				}
+			}()
+		}
+		if se.Ended {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				client, err := w.Client(se.Server)
+				if err == nil {
+					err = recordHistory(ctx, store, client, se)
+				}
+				if err != nil {
+					slog.Error("error recording raid history", slog.String("server", se.Server.ServerId), slog.String("report", se.ReportId), "error", err)
+				}
+			}()
+		}
+		if se.Ended && sheetsClient != nil && se.Server.SpreadsheetId != "" {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				client, err := w.Client(se.Server)
+				if err == nil {
+					err = exportRaid(ctx, sheetsClient, client, se)
+				}
+				if err != nil {
+					slog.Error("error exporting raid to spreadsheet", slog.String("server", se.Server.ServerId), slog.String("report", se.ReportId), "error", err)
+					return
+				}
+				slog.Info("raid exported to spreadsheet", slog.String("server", se.Server.ServerId), slog.String("report", se.ReportId))
+			}()
 		}
-		messageCache.Set(key, msgOut.ID, ttlcache.DefaultTTL)
 	})
 
-	err = dg.Open()
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("GET /healthz", healthHandler)
+	mux.Handle("GET /readyz", readyHandler(map[string]readinessCheck{
+		"discord": discordReady(sessions),
+		"db":      dbReady(db),
+		"wcl":     wclReady(wlClient),
+	}))
+	if config.PprofEnabled {
+		registerPprof(mux, config.PprofToken)
+		slog.Info("pprof is enabled", slog.Bool("auth", config.PprofToken != ""))
+	}
+	api := registerStatsAPI(mux, store, latest)
+	registerGrafanaAPI(mux, store, api)
+	registerOverlay(mux, store, latest, api)
+	if login.enabled() {
+		login.register(mux)
+	}
+	if config.AdminToken != "" {
+		registerAdminAPI(mux, config.AdminToken, store, w)
+		slog.Info("admin api is enabled")
+	}
+	httpServer := serveHTTP(config.HTTPAddr, mux)
+
+	err = openSessions(sessions)
 	if err != nil {
 		panic(err)
 	}
 
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			newConfig, err := loadConfig(cfgPath)
+			if err != nil {
+				slog.Error("error reloading configuration", "error", err)
+				continue
+			}
+			if err := reloadable(newConfig, logLevel, queue); err != nil {
+				slog.Error("error applying configuration", "error", err)
+			}
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
-	dg.Close()
+	slog.Info("shutting down")
+	shuttingDown.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := w.Stop(ctx); err != nil {
+		slog.Error("error stopping watchers", "error", err)
+	}
+	if err := queue.Flush(ctx); err != nil {
+		slog.Error("error flushing pending messages", slog.Int("pending", queue.Len()), "error", err)
+	}
+	stopLocales()
+	stopQueue()
+	stopDispatcher()
+	latest.close()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		slog.Error("error stopping http server", "error", err)
+	}
+	stopUsage()
+	if err := store.FlushUsage(); err != nil {
+		slog.Error("error storing usage", "error", err)
+	}
+	if err := db.Sync(); err != nil {
+		slog.Error("error flushing storage", "error", err)
+	}
+	closeSessions(sessions)
+	if err := db.Close(); err != nil {
+		slog.Error("error closing storage", "error", err)
+	}
+	slog.Info("shutdown complete")
+}
+
+func sendSummary(dg *discordgo.Session, store *storage.Store, se watcher.StatsEvent, threadId string, claims map[string]string, extras summaryExtras) {
+	msg := reportMessage{
+		embeds: []*discordgo.MessageEmbed{constructSummaryEmbed(se, claims, extras)},
+	}
+	if len(extras.Kills) > 0 {
+		msg.components = []discordgo.MessageComponent{constructAnalyzeMenu(discordgo.Locale(se.Server.Locale), se.ReportId, extras.Kills)}
+	}
+	msgOut, err := postMessage(dg, se.Server, threadId, msg)
+	metrics.DiscordMessages.WithLabelValues("send", metrics.Result(err)).Inc()
+	if err != nil {
+		slog.Error("error sending summary", slog.String("server", se.Server.ServerId), slog.String("channel", se.Server.ChannelId), "error", err)
+		return
+	}
+	slog.Info("raid summary sent", slog.String("server", se.Server.ServerId), slog.String("report", se.ReportId))
+	// summaries in raid threads can not start a thread of their own
+	if !se.Server.ScheduledEvents || threadId != "" {
+		return
+	}
+	if ev, ok := scheduledRaid(store, se); ok {
+		if err := attachSummary(dg, se.Server, msgOut, ev); err != nil {
+			slog.Error("error attaching summary to scheduled event", slog.String("server", se.Server.ServerId), slog.String("event", ev.EventId), "error", err)
+		}
+	}
+}
+
+// mentionClaims returns character claims of the server if it opted in to mentions,
+// only the verified ones if the server requires verification.
+func mentionClaims(store *storage.Store, server storage.Server) map[string]string {
+	if !server.MentionClaims {
+		return nil
+	}
+	read := store.ReadClaims
+	if server.VerifiedClaims {
+		read = store.ReadVerifiedClaims
+	}
+	claims, err := read(server.ServerId)
+	if err != nil {
+		slog.Error("error reading claims", slog.String("server", server.ServerId), "error", err)
+		return nil
+	}
+	return claims
+}
+
+// eventTargets returns the operator wide event webhook and the one of the server.
+func eventTargets(config Config, server storage.Server) []events.Target {
+	var targets []events.Target
+	if config.EventsURL != "" {
+		targets = append(targets, events.Target{URL: config.EventsURL, Secret: config.EventsSecret})
+	}
+	if server.EventsURL != "" {
+		targets = append(targets, events.Target{URL: server.EventsURL, Secret: server.EventsSecret, Restricted: true})
+	}
+	return targets
+}
+
+func featureEnabled(store *storage.Store, serverId, name string) bool {
+	global, err := store.ReadFlags(storage.GlobalFlags)
+	if err != nil {
+		slog.Error("error reading global feature flags", "error", err)
+	}
+	server, err := store.ReadFlags(serverId)
+	if err != nil {
+		slog.Error("error reading feature flags", slog.String("server", serverId), "error", err)
+	}
+	return features.Enabled(name, global, server)
 }
 
 func makeKey(se watcher.StatsEvent) string {
 	return se.Server.ServerId + se.Server.ChannelId + se.ReportId
 }
 
+func embedModeOrDefault(mode string) string {
+	if mode == "" {
+		return storage.EmbedModeDetailed
+	}
+	return mode
+}
+
+func themeOrDefault(theme string) string {
+	if theme == "" {
+		return storage.ThemeClassic
+	}
+	return theme
+}
+
+func layoutOrDefault(layout string) string {
+	if layout == "" {
+		return storage.LayoutEmbed
+	}
+	return layout
+}
+
+func medalsOrDefault(medals []string) string {
+	if len(medals) == 0 {
+		medals = defaultMedals
+	}
+	return strings.Join(medals, " ")
+}
+
+func spoilerModeOrDefault(mode string) string {
+	if mode == storage.SpoilersOff {
+		return "off"
+	}
+	return mode
+}
+
+func optionMap(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	m := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		m[opt.Name] = opt
+	}
+	return m
+}
+
+// preferredLocale returns the preferred locale of the server from the state of the shard it belongs to.
+func preferredLocale(sessions []*discordgo.Session, serverId string) discordgo.Locale {
+	for _, s := range sessions {
+		if g, err := s.State.Guild(serverId); err == nil {
+			return discordgo.Locale(g.PreferredLocale)
+		}
+	}
+	return ""
+}
+
+// guildLocale returns the preferred locale of the server, or the locale of the user for servers without one.
+func guildLocale(i *discordgo.InteractionCreate) discordgo.Locale {
+	if i.GuildLocale != nil && i18n.Supported(*i.GuildLocale) {
+		return *i.GuildLocale
+	}
+	return i.Locale
+}
+
 func respond(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
 	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -243,81 +854,64 @@ func respond(s *discordgo.Session, i *discordgo.InteractionCreate, content strin
 	})
 }
 
-func registerCommands(s *discordgo.Session, guild *discordgo.Guild) {
-	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, guild.ID, commands)
-	if err != nil {
-		slog.Error("error registered commands", slog.String("server", guild.ID), "commands", commandNames, "error", err)
-		return
-	}
-	slog.Info("commands registered", slog.String("server", guild.ID), "commands", commandNames)
+func respondEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  1 << 6,
+		},
+	})
 }
 
-func removeCommand(s *discordgo.Session, guildId string, command discordgo.ApplicationCommandInteractionData) {
-	err := s.ApplicationCommandDelete(s.State.User.ID, guildId, command.ID)
-	if err != nil {
-		slog.Error("error removing command", slog.String("server", guildId), slog.String("command", command.Name), "error", err)
-		return
-	}
-	slog.Info("command removed", slog.String("server", guildId), slog.String("command", command.Name))
+// deferResponse acknowledges an interaction whose answer takes longer than Discord waits, editResponse sends it.
+func deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: 1 << 6},
+	})
 }
 
-func constructEmbed(stats watcher.StatsEvent) *discordgo.MessageEmbed {
-	color := 0x2ECC71
-	if !stats.Live {
-		color = 0x95A5A6
-	}
-	return &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("Warcraft Logs\n%v", stats.Title),
-		Description: fmt.Sprintf("```Started by %v\non %v```", stats.StartedBy, stats.StartedAt.Format(time.DateTime)),
-		URL:         stats.URL,
-		Color:       color,
-		Fields: []*discordgo.MessageEmbedField{
-			{
-				Name:   "Top First Deaths",
-				Value:  formatTop(stats.TopFirstDeath),
-				Inline: false,
-			},
-			{
-				Name:   "Top Deaths Before Wipe",
-				Value:  formatTop(stats.TopDeath),
-				Inline: false,
-			},
-		},
-		Footer: &discordgo.MessageEmbedFooter{
-			Text: "Last upload",
-		},
-		Timestamp: stats.LastUpload.Format(time.RFC3339),
+func editResponse(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		slog.Warn("error editing interaction response", slog.String("server", i.GuildID), "error", err)
 	}
 }
 
-func formatTop(top []warcraftlogs.PlayerTop) string {
-	if len(top) == 0 {
-		return "``` ```"
-	}
-	var sb strings.Builder
-	sb.Grow(128)
-	sb.WriteString("```")
-	for i, t := range top {
-		sb.WriteString(padRight(t.Name, 12))
-		sb.WriteString(padLeft(strconv.Itoa(t.Value), 12))
-		if i != len(top)-1 {
-			sb.WriteRune('\n')
-		}
+// isMember reports whether the user is a member of the guild, the state is asked before the api.
+func isMember(ctx context.Context, s *discordgo.Session, guildId, userId string) bool {
+	if _, err := s.State.Member(guildId, userId); err == nil {
+		return true
 	}
-	sb.WriteString("```")
-	return sb.String()
+	_, err := s.GuildMember(guildId, userId, discordgo.WithContext(ctx))
+	return err == nil
+}
+
+func respondFile(s *discordgo.Session, i *discordgo.InteractionCreate, content, name string, data []byte) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Files:   []*discordgo.File{{Name: name, ContentType: "application/json", Reader: bytes.NewReader(data)}},
+			Flags:   1 << 6,
+		},
+	})
 }
 
-func padRight(s string, to int) string {
-	if diff := to - utf8.RuneCountInString(s); diff > 0 {
-		return s + strings.Repeat(" ", diff)
+func registerCommands(s *discordgo.Session, guild *discordgo.Guild, cmds []*discordgo.ApplicationCommand) {
+	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, guild.ID, cmds)
+	if err != nil {
+		slog.Error("error registered commands", slog.String("server", guild.ID), "commands", commandNames(cmds), "error", err)
+		return
 	}
-	return s
+	slog.Info("commands registered", slog.String("server", guild.ID), "commands", commandNames(cmds))
 }
 
-func padLeft(s string, to int) string {
-	if diff := to - utf8.RuneCountInString(s); diff > 0 {
-		return strings.Repeat(" ", diff) + s
+func removeCommand(s *discordgo.Session, guildId string, command discordgo.ApplicationCommandInteractionData) {
+	err := s.ApplicationCommandDelete(s.State.User.ID, guildId, command.ID)
+	if err != nil {
+		slog.Error("error removing command", slog.String("server", guildId), slog.String("command", command.Name), "error", err)
+		return
 	}
-	return s
+	slog.Info("command removed", slog.String("server", guildId), slog.String("command", command.Name))
 }
@@ -0,0 +1,175 @@
+package mechanics
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"bot/warcraftlogs"
+)
+
+const (
+	TypeDamage = "damage"
+	TypeDebuff = "debuff"
+)
+
+// Mechanic is a failure counted every time a player takes damage from or gets a debuff of the ability.
+type Mechanic struct {
+	Name    string `json:"name"`
+	Ability int    `json:"ability"`
+	Type    string `json:"type"`
+}
+
+// Encounter lists the abilities of a boss that a player could have avoided. Damage taken from
+// Avoidable abilities is summed up, Failures are counted.
+type Encounter struct {
+	Name      string     `json:"name"`
+	Avoidable []int      `json:"avoidable"`
+	Failures  []Mechanic `json:"failures"`
+}
+
+// Table maps encounter ids to their mechanics, the file keys are the ids as strings.
+type Table map[int]Encounter
+
+var (
+	mu    sync.RWMutex
+	table Table
+)
+
+// Load replaces the mechanics with the ones defined in the json file.
+func Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var raw map[string]Encounter
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("error parsing mechanics file %v: %w", path, err)
+	}
+	t := make(Table, len(raw))
+	for key, enc := range raw {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid encounter id %q in %v", key, path)
+		}
+		if len(enc.Avoidable) == 0 && len(enc.Failures) == 0 {
+			return fmt.Errorf("encounter %v in %v has no abilities", key, path)
+		}
+		for _, m := range enc.Failures {
+			if m.Type != TypeDamage && m.Type != TypeDebuff {
+				return fmt.Errorf("invalid mechanic type %q of %v in %v", m.Type, m.Name, path)
+			}
+		}
+		t[id] = enc
+	}
+
+	mu.Lock()
+	table = t
+	mu.Unlock()
+	slog.Info("mechanics loaded", slog.String("file", path), slog.Int("encounters", len(t)))
+	return nil
+}
+
+// Lookup returns the mechanics of the encounter, encounters without any abilities have nothing to query.
+func Lookup(encounterId int) (Encounter, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	enc, ok := table[encounterId]
+	return enc, ok && (len(enc.Avoidable) > 0 || len(enc.Failures) > 0)
+}
+
+// FilterExpression selects the events of the encounter's abilities that hit players.
+func (e Encounter) FilterExpression() string {
+	damage := slices.Clone(e.Avoidable)
+	var debuffs []int
+	for _, m := range e.Failures {
+		if m.Type == TypeDebuff {
+			debuffs = append(debuffs, m.Ability)
+		} else {
+			damage = append(damage, m.Ability)
+		}
+	}
+	var parts []string
+	if len(damage) > 0 {
+		parts = append(parts, fmt.Sprintf(`(type = "damage" and ability.id in (%v))`, joinInts(damage)))
+	}
+	if len(debuffs) > 0 {
+		parts = append(parts, fmt.Sprintf(`(type = "applydebuff" and ability.id in (%v))`, joinInts(debuffs)))
+	}
+	return `target.type = "player" and (` + strings.Join(parts, " or ") + ")"
+}
+
+// Offender is the avoidable damage and the mechanic failures of a player over a report.
+type Offender struct {
+	Name            string
+	AvoidableDamage int
+	Failures        int
+}
+
+// Tally adds the events of a fight of the encounter to the offenders, keyed by player name.
+func (e Encounter) Tally(events []warcraftlogs.AbilityEvent, offenders map[string]*Offender) {
+	avoidable := make(map[int]bool, len(e.Avoidable))
+	for _, id := range e.Avoidable {
+		avoidable[id] = true
+	}
+	failures := make(map[int]string, len(e.Failures))
+	for _, m := range e.Failures {
+		failures[m.Ability] = m.Type
+	}
+
+	for _, ev := range events {
+		if ev.Target.Name == "" {
+			continue
+		}
+		o, ok := offenders[ev.Target.Name]
+		if !ok {
+			o = &Offender{Name: ev.Target.Name}
+			offenders[ev.Target.Name] = o
+		}
+		switch {
+		case ev.Type == "damage" && avoidable[ev.AbilityGameID]:
+			o.AvoidableDamage += ev.Amount + ev.Absorbed
+			if failures[ev.AbilityGameID] == TypeDamage {
+				o.Failures++
+			}
+		case ev.Type == "damage" && failures[ev.AbilityGameID] == TypeDamage:
+			o.Failures++
+		case ev.Type == "applydebuff" && failures[ev.AbilityGameID] == TypeDebuff:
+			o.Failures++
+		}
+	}
+}
+
+// Worst returns up to n offenders, ordered by avoidable damage and then by failures.
+func Worst(offenders map[string]*Offender, n int) []Offender {
+	out := make([]Offender, 0, len(offenders))
+	for _, o := range offenders {
+		if o.AvoidableDamage > 0 || o.Failures > 0 {
+			out = append(out, *o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AvoidableDamage != out[j].AvoidableDamage {
+			return out[i].AvoidableDamage > out[j].AvoidableDamage
+		}
+		return out[i].Failures > out[j].Failures
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func joinInts(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ", ")
+}
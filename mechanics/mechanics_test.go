@@ -0,0 +1,100 @@
+package mechanics
+
+import (
+	"testing"
+
+	"bot/warcraftlogs"
+)
+
+func event(typ string, ability, amount, absorbed int, target string) warcraftlogs.AbilityEvent {
+	ev := warcraftlogs.AbilityEvent{Type: typ, AbilityGameID: ability, Amount: amount, Absorbed: absorbed}
+	ev.Target.Name = target
+	return ev
+}
+
+func TestTally(t *testing.T) {
+	enc := Encounter{
+		Avoidable: []int{1, 2},
+		Failures: []Mechanic{
+			{Name: "Puddle", Ability: 2, Type: TypeDamage},
+			{Name: "Beam", Ability: 3, Type: TypeDamage},
+			{Name: "Mark", Ability: 4, Type: TypeDebuff},
+		},
+	}
+	tests := []struct {
+		name   string
+		events []warcraftlogs.AbilityEvent
+		want   map[string]Offender
+	}{
+		{
+			name:   "avoidable damage includes absorbed",
+			events: []warcraftlogs.AbilityEvent{event("damage", 1, 100, 50, "a"), event("damage", 1, 10, 0, "a")},
+			want:   map[string]Offender{"a": {Name: "a", AvoidableDamage: 160}},
+		},
+		{
+			name:   "avoidable failure counts damage and a failure",
+			events: []warcraftlogs.AbilityEvent{event("damage", 2, 100, 0, "a")},
+			want:   map[string]Offender{"a": {Name: "a", AvoidableDamage: 100, Failures: 1}},
+		},
+		{
+			name:   "damage failure",
+			events: []warcraftlogs.AbilityEvent{event("damage", 3, 100, 0, "a"), event("damage", 3, 100, 0, "b")},
+			want:   map[string]Offender{"a": {Name: "a", Failures: 1}, "b": {Name: "b", Failures: 1}},
+		},
+		{
+			name:   "debuff failure",
+			events: []warcraftlogs.AbilityEvent{event("applydebuff", 4, 0, 0, "a")},
+			want:   map[string]Offender{"a": {Name: "a", Failures: 1}},
+		},
+		{
+			name:   "debuff damage and damage debuffs are not failures",
+			events: []warcraftlogs.AbilityEvent{event("damage", 4, 100, 0, "a"), event("applydebuff", 3, 0, 0, "a")},
+			want:   map[string]Offender{"a": {Name: "a"}},
+		},
+		{
+			name:   "events without a target are skipped",
+			events: []warcraftlogs.AbilityEvent{event("damage", 1, 100, 0, "")},
+			want:   map[string]Offender{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offenders := make(map[string]*Offender)
+			enc.Tally(tt.events, offenders)
+			if len(offenders) != len(tt.want) {
+				t.Fatalf("got %v offenders, want %v", len(offenders), len(tt.want))
+			}
+			for name, want := range tt.want {
+				if got, ok := offenders[name]; !ok || *got != want {
+					t.Errorf("offender %v = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTallyAcrossFights(t *testing.T) {
+	enc := Encounter{Avoidable: []int{1}}
+	offenders := make(map[string]*Offender)
+	enc.Tally([]warcraftlogs.AbilityEvent{event("damage", 1, 100, 0, "a")}, offenders)
+	enc.Tally([]warcraftlogs.AbilityEvent{event("damage", 1, 50, 0, "a")}, offenders)
+	if got := offenders["a"].AvoidableDamage; got != 150 {
+		t.Fatalf("avoidable damage %v, want 150", got)
+	}
+}
+
+func TestWorst(t *testing.T) {
+	offenders := map[string]*Offender{
+		"a": {Name: "a", AvoidableDamage: 100, Failures: 1},
+		"b": {Name: "b", AvoidableDamage: 100, Failures: 3},
+		"c": {Name: "c", AvoidableDamage: 500},
+		"d": {Name: "d"},
+	}
+	got := Worst(offenders, 2)
+	if len(got) != 2 || got[0].Name != "c" || got[1].Name != "b" {
+		t.Fatalf("got %+v, want c and b", got)
+	}
+	if got := Worst(offenders, 10); len(got) != 3 {
+		t.Fatalf("got %v offenders, players without damage or failures must be left out", len(got))
+	}
+}
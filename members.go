@@ -0,0 +1,177 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"bot/i18n"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+func (c *serverCommands) progress(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	server, ok := c.readServer(s, i)
+	if !ok {
+		return
+	}
+	if !isWarcraft(*server) {
+		respond(s, i, i18n.T(i.Locale, "progress.unavailable"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	profile, err := c.guilds.profile(ctx, server.WlGuildId)
+	if err != nil {
+		slog.Error("error loading raider.io profile", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "progress.unavailable"))
+		return
+	}
+	raids := profile.Raids()
+	if len(raids) == 0 {
+		respond(s, i, i18n.T(i.Locale, "progress.none"))
+		return
+	}
+	lines := []string{"💡 " + i18n.T(i.Locale, "progress.title", profile.Name)}
+	for _, rp := range raids {
+		lines = append(lines, raidName(rp.Raid)+": "+formatProgress(i.Locale, rp, profile.Region))
+	}
+	lines = append(lines, "<"+profile.ProfileURL+">")
+	respond(s, i, strings.Join(lines, "\n"))
+}
+
+func (c *serverCommands) leaderboard(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	server, ok := c.readServer(s, i)
+	if !ok {
+		return
+	}
+	summaries, err := c.store.ReportSummaries(i.GuildID)
+	if err != nil {
+		slog.Error("error reading raid history", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	if len(summaries) == 0 {
+		respond(s, i, i18n.T(i.Locale, "leaderboard.empty"))
+		return
+	}
+	respondEmbed(s, i, constructLeaderboardEmbed(i.Locale, *server, summaries))
+}
+
+func (c *serverCommands) verify(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !c.login.enabled() {
+		respond(s, i, i18n.T(i.Locale, "verify.unavailable"))
+		return
+	}
+	server, ok := c.readServer(s, i)
+	if !ok {
+		return
+	}
+	if !isWarcraft(*server) {
+		respond(s, i, i18n.T(i.Locale, "verify.unavailable"))
+		return
+	}
+	serverName := i.GuildID
+	if g, err := s.State.Guild(i.GuildID); err == nil {
+		serverName = g.Name
+	}
+	link := c.login.start(verifyRequest{
+		ServerId:   i.GuildID,
+		ServerName: serverName,
+		UserId:     i.Member.User.ID,
+		UserName:   i.Member.User.String(),
+		Locale:     i.Locale,
+	})
+	respond(s, i, i18n.T(i.Locale, "verify.link", link, int(verifyTimeout.Minutes())))
+}
+
+func (c *serverCommands) claim(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	character := optionMap(data.Options)["character"].StringValue()
+	server, _ := c.store.ReadServer(i.GuildID)
+	if server != nil && server.VerifiedClaims {
+		respond(s, i, i18n.T(i.Locale, "claim.verification_required"))
+		return
+	}
+	if c.guilds.rosterEnabled() {
+		if server != nil && isWarcraft(*server) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+			roster, err := c.guilds.roster(ctx, server.WlGuildId)
+			cancel()
+			// an unavailable roster must not block claims
+			if err != nil {
+				slog.Warn("error loading guild roster", slog.String("server", i.GuildID), "error", err)
+			} else if members := roster.Named(character); len(members) == 0 {
+				respond(s, i, i18n.T(i.Locale, "claim.not_in_roster", character))
+				return
+			} else if len(members) > 1 {
+				respond(s, i, i18n.T(i.Locale, "claim.ambiguous", character))
+				return
+			}
+		}
+	}
+	owner, err := c.store.SaveClaim(i.GuildID, character, i.Member.User.ID)
+	if err != nil {
+		slog.Error("error saving claim", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	if owner != i.Member.User.ID {
+		respond(s, i, i18n.T(i.Locale, "claim.taken", character, owner))
+		return
+	}
+	slog.Info("character claimed", slog.String("server", i.GuildID), slog.String("character", character), slog.String("user", i.Member.User.ID))
+	respond(s, i, i18n.T(i.Locale, "claim.done", character))
+}
+
+func (c *serverCommands) unclaim(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	character := optionMap(data.Options)["character"].StringValue()
+	claims, err := c.store.ReadClaims(i.GuildID)
+	if err == nil {
+		owner, ok := claims[strings.ToLower(character)]
+		isAdmin := i.Member.Permissions&discordgo.PermissionAdministrator != 0
+		if ok && owner != i.Member.User.ID && !isAdmin {
+			respond(s, i, i18n.T(i.Locale, "unclaim.not_owner", character, owner))
+			return
+		}
+		err = c.store.DeleteClaim(i.GuildID, character)
+	}
+	if err != nil {
+		slog.Error("error deleting claim", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	slog.Info("character unclaimed", slog.String("server", i.GuildID), slog.String("character", character), slog.String("user", i.Member.User.ID))
+	respond(s, i, i18n.T(i.Locale, "unclaim.done", character))
+}
+
+func (c *serverCommands) sendFeedback(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	channelId := cmp.Or(c.config.FeedbackChannelId, c.config.OpsChannelId)
+	if channelId == "" {
+		respond(s, i, i18n.T(i.Locale, "feedback.unavailable"))
+		return
+	}
+	if c.feedback.Has(i.Member.User.ID) {
+		respond(s, i, i18n.T(i.Locale, "feedback.cooldown"))
+		return
+	}
+	server, err := c.store.ReadServer(i.GuildID)
+	if err != nil {
+		slog.Error("error reading configuration", slog.String("server", i.GuildID), "error", err)
+	}
+	guildName := i.GuildID
+	if guild, err := s.State.Guild(i.GuildID); err == nil {
+		guildName = guild.Name
+	}
+	text := optionMap(data.Options)["message"].StringValue()
+	if _, err := s.ChannelMessageSendEmbed(channelId, constructFeedbackEmbed(i, guildName, server, text)); err != nil {
+		slog.Error("error forwarding feedback", slog.String("server", i.GuildID), slog.String("channel", channelId), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	c.feedback.Set(i.Member.User.ID, struct{}{}, ttlcache.DefaultTTL)
+	slog.Info("feedback forwarded", slog.String("server", i.GuildID), slog.String("user", i.Member.User.ID))
+	respond(s, i, i18n.T(i.Locale, "feedback.sent"))
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	WCLRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wcl_requests_total",
+		Help: "Warcraft Logs API requests by result.",
+	}, []string{"result"})
+
+	WCLRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "wcl_request_duration_seconds",
+		Help:    "Warcraft Logs API request latency.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	PollDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "watcher_poll_duration_seconds",
+		Help:    "Duration of a single server poll including report details.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60},
+	})
+
+	ActiveWatchLoops = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "watcher_active_loops",
+		Help: "Number of running watch loops.",
+	})
+
+	DiscordMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "discord_messages_total",
+		Help: "Discord messages sent, edited or deleted by operation and result.",
+	}, []string{"operation", "result"})
+
+	Errors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "errors_total",
+		Help: "Operational errors by component.",
+	}, []string{"component"})
+)
+
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bot/i18n"
+	"bot/notify"
+	"bot/storage"
+	"bot/version"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// mirrors delivers report updates to the chats servers mirror their channel to,
+// the message of a report is edited in place like the Discord one.
+type mirrors struct {
+	chats    map[string]notify.Chat
+	messages *ttlcache.Cache[string, string]
+}
+
+func newMirrors(chats ...notify.Chat) *mirrors {
+	messages := ttlcache.New[string, string](
+		ttlcache.WithTTL[string, string](24 * time.Hour),
+	)
+	go messages.Start()
+	m := &mirrors{chats: make(map[string]notify.Chat), messages: messages}
+	for _, c := range chats {
+		m.chats[c.Name()] = c
+	}
+	return m
+}
+
+func (m *mirrors) available(platform string) bool {
+	_, ok := m.chats[platform]
+	return ok
+}
+
+// check verifies that the bot can post to the target chat of the platform.
+func (m *mirrors) check(ctx context.Context, platform, target string) error {
+	c, ok := m.chats[platform]
+	if !ok {
+		return fmt.Errorf("unknown platform %v", platform)
+	}
+	return c.Check(ctx, target)
+}
+
+func (m *mirrors) Name() string {
+	return "mirror"
+}
+
+// Notify posts or updates the message of the report in every mirror of the server.
+func (m *mirrors) Notify(ctx context.Context, se watcher.StatsEvent) error {
+	if len(se.Server.Mirrors) == 0 {
+		return nil
+	}
+	msg := mirrorMessage(se)
+	var errs []error
+	for platform, target := range se.Server.Mirrors {
+		c, ok := m.chats[platform]
+		if !ok {
+			continue
+		}
+		key := platform + ":" + target + ":" + se.ReportId
+		if item := m.messages.Get(key); item != nil {
+			if err := c.Update(ctx, target, item.Value(), msg); err != nil {
+				errs = append(errs, fmt.Errorf("updating %v message: %w", platform, err))
+			}
+			continue
+		}
+		id, err := c.Post(ctx, target, msg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sending %v message: %w", platform, err))
+			continue
+		}
+		m.messages.Set(key, id, ttlcache.DefaultTTL)
+	}
+	return errors.Join(errs...)
+}
+
+// mirrorMessage is the detailed report embed without Discord markup. Spoiler tags have no counterpart
+// on other platforms, so servers hiding boss names get generic labels there.
+func mirrorMessage(stats watcher.StatsEvent) notify.Message {
+	locale := discordgo.Locale(stats.Server.Locale)
+	mode := stats.Server.SpoilerMode
+	if mode == storage.SpoilersTags {
+		mode = storage.SpoilersGeneric
+	}
+
+	var bosses []string
+	for i, b := range stats.Bosses {
+		label := bossLabel(b, i, mode, locale)
+		if mode == storage.SpoilersGeneric {
+			bosses = append(bosses, padRight(label, 24)+padLeft(fmt.Sprint(b.Kills+b.Wipes), 8))
+			continue
+		}
+		bosses = append(bosses, padRight(label, 24)+padLeft(fmt.Sprintf("%d / %d", b.Kills, b.Wipes), 8))
+	}
+	return notify.Message{
+		Title:       siteName(stats.Server) + " · " + stats.Title,
+		URL:         stats.URL,
+		LinkText:    i18n.T(locale, "embed.open_report"),
+		Description: i18n.T(locale, "mirror.started_by", stats.StartedBy),
+		Color:       embedColor(stats),
+		Fields: []notify.Field{
+			{Name: formatTallyTitle(stats), Value: joinOrDash(bosses), Code: true},
+			{Name: i18n.T(locale, "embed.top_first_deaths"), Value: formatPlainTop(stats.TopFirstDeath), Code: true},
+			{Name: i18n.T(locale, "embed.top_deaths"), Value: formatPlainTop(stats.TopDeath), Code: true},
+		},
+		Footer: version.Version + " · " + i18n.T(locale, "mirror.footer", formatInterval(locale, stats.PollInterval)),
+	}
+}
+
+func formatPlainTop(top []warcraftlogs.PlayerTop) string {
+	lines := make([]string, 0, len(top))
+	for i, t := range top {
+		lines = append(lines, fmt.Sprintf("%d. %v%v", i+1, padRight(t.Name, 12), padLeft(fmt.Sprint(t.Value), 12)))
+	}
+	return joinOrDash(lines)
+}
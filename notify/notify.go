@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+
+	"bot/watcher"
+)
+
+// Notifier delivers report updates to a chat platform, the message of a report is edited in place by later updates.
+type Notifier interface {
+	// Name is the platform name, deliveries are keyed by it.
+	Name() string
+	Notify(ctx context.Context, se watcher.StatsEvent) error
+}
+
+// Chat posts platform neutral messages to a chat platform other than Discord, servers mirror their channel to it.
+type Chat interface {
+	// Name is the platform name servers use to pick the chat.
+	Name() string
+	// Check verifies that the bot is a member of the target chat and can post to it.
+	Check(ctx context.Context, target string) error
+	// Post sends the message to the target chat and returns the id Update needs to replace it.
+	Post(ctx context.Context, target string, msg Message) (string, error)
+	Update(ctx context.Context, target, id string, msg Message) error
+}
+
+// Message is a report update without platform specific markup, notifiers render it their own way.
+type Message struct {
+	Title       string
+	URL         string
+	LinkText    string
+	Description string
+	Color       int
+	Fields      []Field
+	Footer      string
+}
+
+type Field struct {
+	Name  string
+	Value string
+	// Code marks values laid out as a table, rendered in a monospace block.
+	Code bool
+}
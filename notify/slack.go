@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const slackAPI = "https://slack.com/api/"
+
+// Slack posts Block Kit messages with a bot token, targets are channel ids.
+type Slack struct {
+	resty *resty.Client
+}
+
+func NewSlack(token string) *Slack {
+	return &Slack{resty: resty.New().SetTimeout(10 * time.Second).SetAuthToken(token)}
+}
+
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+type slackResp struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	TS      string `json:"ts"`
+	Channel struct {
+		IsMember bool `json:"is_member"`
+	} `json:"channel"`
+}
+
+func (s *Slack) Check(ctx context.Context, channel string) error {
+	// read methods take form or query arguments, JSON bodies are only accepted by write methods
+	var out slackResp
+	resp, err := s.resty.R().
+		SetContext(ctx).
+		SetQueryParam("channel", channel).
+		SetResult(&out).
+		Get(slackAPI + "conversations.info")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("slack conversations.info: %s", resp.Status())
+	}
+	if !out.OK {
+		return errors.New("slack conversations.info: " + out.Error)
+	}
+	if !out.Channel.IsMember {
+		return errors.New("slack bot is not a member of the channel")
+	}
+	return nil
+}
+
+func (s *Slack) Post(ctx context.Context, channel string, msg Message) (string, error) {
+	out, err := s.call(ctx, "chat.postMessage", s.payload(channel, msg))
+	return out.TS, err
+}
+
+func (s *Slack) Update(ctx context.Context, channel, ts string, msg Message) error {
+	payload := s.payload(channel, msg)
+	payload["ts"] = ts
+	_, err := s.call(ctx, "chat.update", payload)
+	return err
+}
+
+// call invokes a Web API method, Slack reports most errors with status 200 and ok set to false.
+func (s *Slack) call(ctx context.Context, method string, payload map[string]any) (slackResp, error) {
+	var out slackResp
+	resp, err := s.resty.R().
+		SetContext(ctx).
+		SetBody(payload).
+		SetResult(&out).
+		Post(slackAPI + method)
+	if err != nil {
+		return out, err
+	}
+	if resp.IsError() {
+		return out, fmt.Errorf("slack %v: %s", method, resp.Status())
+	}
+	if !out.OK {
+		return out, errors.New("slack " + method + ": " + out.Error)
+	}
+	return out, nil
+}
+
+// payload lays the message out as blocks inside an attachment, attachments are the only way to get a colored bar.
+func (s *Slack) payload(channel string, msg Message) map[string]any {
+	text := func(t string) map[string]any {
+		return map[string]any{"type": "mrkdwn", "text": t}
+	}
+	blocks := []map[string]any{
+		{"type": "header", "text": map[string]any{"type": "plain_text", "text": cut(msg.Title, 150)}},
+		{"type": "section", "text": text(fmt.Sprintf("<%v|%v>\n%v", msg.URL, slackEscape(msg.LinkText, 100), slackEscape(msg.Description, 2500)))},
+	}
+	for _, f := range msg.Fields {
+		name := "*" + slackEscape(f.Name, 250) + "*\n"
+		left := 3000 - utf8.RuneCountInString(name)
+		var value string
+		if f.Code {
+			value = "```" + slackEscape(f.Value, left-6) + "```"
+		} else {
+			value = slackEscape(f.Value, left)
+		}
+		blocks = append(blocks, map[string]any{"type": "section", "text": text(name + value)})
+	}
+	if msg.Footer != "" {
+		blocks = append(blocks, map[string]any{"type": "context", "elements": []any{text(slackEscape(msg.Footer, 2000))}})
+	}
+	return map[string]any{
+		"channel": channel,
+		"text":    slackEscape(msg.Title, 3000),
+		"attachments": []any{map[string]any{
+			"color":  fmt.Sprintf("#%06x", msg.Color),
+			"blocks": blocks,
+		}},
+	}
+}
+
+var slackEntities = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackEscape escapes the characters mrkdwn reads as markup, so text from reports can neither mention channels
+// nor inject links, and cuts the result to n runes without splitting an entity.
+func slackEscape(s string, n int) string {
+	escaped := slackEntities.Replace(s)
+	if utf8.RuneCountInString(escaped) <= n {
+		return escaped
+	}
+	var sb strings.Builder
+	left := n - 1
+	for _, r := range s {
+		e := slackEntities.Replace(string(r))
+		if utf8.RuneCountInString(e) > left {
+			break
+		}
+		left -= utf8.RuneCountInString(e)
+		sb.WriteString(e)
+	}
+	return sb.String() + "…"
+}
+
+// cut limits s to n runes, platforms reject messages exceeding their limits.
+func cut(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const telegramAPI = "https://api.telegram.org/bot"
+
+// Telegram posts HTML messages through the Bot API, targets are chat ids of groups the bot is a member of.
+type Telegram struct {
+	token string
+	resty *resty.Client
+}
+
+func NewTelegram(token string) *Telegram {
+	return &Telegram{token: token, resty: resty.New().SetTimeout(10 * time.Second)}
+}
+
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+type telegramResp struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      struct {
+		MessageId int64 `json:"message_id"`
+	} `json:"result"`
+}
+
+// Check asks for the chat, the Bot API only knows chats the bot is a member of.
+func (t *Telegram) Check(ctx context.Context, chatId string) error {
+	_, err := t.call(ctx, "getChat", map[string]any{"chat_id": chatId})
+	return err
+}
+
+func (t *Telegram) Post(ctx context.Context, chatId string, msg Message) (string, error) {
+	id, err := t.call(ctx, "sendMessage", t.payload(chatId, msg))
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+func (t *Telegram) Update(ctx context.Context, chatId, messageId string, msg Message) error {
+	payload := t.payload(chatId, msg)
+	payload["message_id"] = messageId
+	_, err := t.call(ctx, "editMessageText", payload)
+	// edits without changes are rejected, the message is up to date then
+	if err != nil && strings.Contains(err.Error(), "message is not modified") {
+		return nil
+	}
+	return err
+}
+
+func (t *Telegram) call(ctx context.Context, method string, payload map[string]any) (int64, error) {
+	var out telegramResp
+	resp, err := t.resty.R().
+		SetContext(ctx).
+		SetBody(payload).
+		SetResult(&out).
+		SetError(&out).
+		Post(telegramAPI + t.token + "/" + method)
+	if err != nil {
+		return 0, t.redact(err)
+	}
+	if resp.IsError() || !out.OK {
+		if out.Description == "" {
+			return 0, fmt.Errorf("telegram %v: %s", method, resp.Status())
+		}
+		return 0, errors.New("telegram " + method + ": " + out.Description)
+	}
+	return out.Result.MessageId, nil
+}
+
+// redact keeps the bot token, which is part of every request url, out of errors that end up in logs.
+func (t *Telegram) redact(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		err = &url.Error{Op: uerr.Op, URL: strings.ReplaceAll(uerr.URL, t.token, "<token>"), Err: uerr.Err}
+	}
+	if strings.Contains(err.Error(), t.token) {
+		return errors.New(strings.ReplaceAll(err.Error(), t.token, "<token>"))
+	}
+	return err
+}
+
+// telegramText builds the HTML message, the text is cut to the limit before it is escaped and wrapped in tags
+// so a cut never leaves a tag open.
+type telegramText struct {
+	sb   strings.Builder
+	left int
+}
+
+func (t *telegramText) write(open, text, close string) {
+	if t.left <= 0 || text == "" {
+		return
+	}
+	text = cut(text, t.left)
+	t.left -= utf8.RuneCountInString(text)
+	t.sb.WriteString(open + html.EscapeString(text) + close)
+}
+
+func (t *Telegram) payload(chatId string, msg Message) map[string]any {
+	text := telegramText{left: 4096}
+	text.write("<b>", msg.Title, "</b>")
+	text.write("", "\n", "")
+	text.write("", msg.Description, "")
+	for _, f := range msg.Fields {
+		text.write("", "\n\n", "")
+		text.write("<b>", f.Name, "</b>")
+		text.write("", "\n", "")
+		if f.Code {
+			text.write("<pre>", f.Value, "</pre>")
+		} else {
+			text.write("", f.Value, "")
+		}
+	}
+	if msg.Footer != "" {
+		text.write("", "\n\n", "")
+		text.write("<i>", msg.Footer, "</i>")
+	}
+	return map[string]any{
+		"chat_id":                  chatId,
+		"text":                     text.sb.String(),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+		"reply_markup": map[string]any{
+			"inline_keyboard": [][]map[string]string{{{"text": msg.LinkText, "url": msg.URL}}},
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bot/errreport"
+)
+
+// Queue delivers outgoing chat requests one at a time under a global rate limit.
+// Jobs are keyed, a job enqueued under a pending key replaces the pending one but keeps its place in line,
+// so bursts of edits to the same message collapse into the latest one.
+type Queue struct {
+	interval atomic.Int64
+	wake     chan struct{}
+
+	mu      sync.Mutex
+	order   []string
+	pending map[string]func()
+	running bool
+}
+
+func New(perSecond float64) *Queue {
+	q := &Queue{
+		wake:    make(chan struct{}, 1),
+		pending: make(map[string]func()),
+	}
+	q.SetRate(perSecond)
+	return q
+}
+
+// SetRate changes the number of requests delivered per second, it applies from the next request on.
+func (q *Queue) SetRate(perSecond float64) {
+	q.interval.Store(int64(float64(time.Second) / perSecond))
+}
+
+func (q *Queue) Enqueue(key string, job func()) {
+	q.mu.Lock()
+	if _, ok := q.pending[key]; !ok {
+		q.order = append(q.order, key)
+	}
+	q.pending[key] = job
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.order)
+}
+
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		job, ok := q.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+				continue
+			}
+		}
+
+		q.run(ctx, job)
+
+		limiter := time.NewTimer(time.Duration(q.interval.Load()))
+		select {
+		case <-ctx.Done():
+			limiter.Stop()
+			return
+		case <-limiter.C:
+		}
+	}
+}
+
+// Flush waits until every pending job is delivered.
+func (q *Queue) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		q.mu.Lock()
+		idle := len(q.order) == 0 && !q.running
+		q.mu.Unlock()
+		if idle {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) pop() (func(), bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.order) == 0 {
+		return nil, false
+	}
+	key := q.order[0]
+	q.order = q.order[1:]
+	job := q.pending[key]
+	delete(q.pending, key)
+	q.running = true
+	return job, true
+}
+
+func (q *Queue) run(ctx context.Context, job func()) {
+	defer func() {
+		q.mu.Lock()
+		q.running = false
+		q.mu.Unlock()
+	}()
+	defer errreport.Recover(ctx, map[string]string{"component": "outbox"})
+	job()
+}
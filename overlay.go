@@ -0,0 +1,137 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"bot/events"
+	"bot/i18n"
+	"bot/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+//go:embed overlay.html
+var overlayPage string
+
+var overlayTemplate = template.Must(template.New("overlay").Parse(overlayPage))
+
+// overlayHeartbeat keeps idle event streams open through proxies between updates.
+const overlayHeartbeat = 30 * time.Second
+
+type overlayLabels struct {
+	Lang      string
+	Title     string
+	Waiting   string
+	Pull      string
+	Deaths    string
+	Night     string
+	Kill      string
+	TopDeaths string
+}
+
+type overlayAPI struct {
+	store  *storage.Store
+	latest *latestStats
+	auth   *statsAPI
+}
+
+// registerOverlay serves a browser source page for streaming software showing the current pull and the top deaths
+// of the night, updated through server-sent events. Browser sources can not set headers, the token is passed as
+// the token query parameter.
+func registerOverlay(mux *http.ServeMux, store *storage.Store, latest *latestStats, auth *statsAPI) {
+	api := &overlayAPI{store: store, latest: latest, auth: auth}
+	mux.HandleFunc("GET /overlay/servers/{id}", api.page)
+	mux.HandleFunc("GET /overlay/servers/{id}/events", api.events)
+}
+
+func (api *overlayAPI) page(w http.ResponseWriter, r *http.Request) {
+	serverId := r.PathValue("id")
+	if !api.auth.authorized(r, serverId) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	locale := i18n.Fallback
+	if server, err := api.store.ReadServer(serverId); err == nil && server != nil && server.Locale != "" {
+		locale = discordgo.Locale(server.Locale)
+	}
+	labels := overlayLabels{
+		Lang:      strings.SplitN(string(locale), "-", 2)[0],
+		Title:     i18n.T(locale, "overlay.title"),
+		Waiting:   i18n.T(locale, "overlay.waiting"),
+		Pull:      i18n.T(locale, "overlay.pull"),
+		Deaths:    i18n.T(locale, "overlay.deaths"),
+		Night:     i18n.T(locale, "overlay.night"),
+		Kill:      i18n.T(locale, "overlay.kill"),
+		TopDeaths: i18n.T(locale, "embed.top_deaths"),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := overlayTemplate.Execute(w, labels); err != nil {
+		slog.Error("error rendering overlay", slog.String("server", serverId), "error", err)
+	}
+}
+
+func (api *overlayAPI) events(w http.ResponseWriter, r *http.Request) {
+	serverId := r.PathValue("id")
+	if !api.auth.authorized(r, serverId) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	updates, unsubscribe := api.latest.subscribe(serverId)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(data []byte) bool {
+		if _, err := fmt.Fprintf(w, "event: stats\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	if se, ok := api.latest.get(serverId); ok {
+		data, _ := json.Marshal(apiLatest{ServerId: serverId, NextRefresh: se.NextRefresh, Report: events.NewReport(se)})
+		send(data)
+	} else {
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(overlayHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case se, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(apiLatest{ServerId: serverId, NextRefresh: se.NextRefresh, Report: events.NewReport(se)})
+			if !send(data) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// overlayURL is the browser source address of the server, a path when the public url of the bot is unknown.
+func overlayURL(publicURL, serverId, token string) string {
+	return strings.TrimSuffix(publicURL, "/") + "/overlay/servers/" + serverId + "?token=" + token
+}
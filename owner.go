@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"bot/features"
+	"bot/i18n"
+	"bot/outbox"
+	"bot/storage"
+	"bot/version"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ownerCommands struct {
+	ownerId  string
+	store    *storage.Store
+	w        *watcher.Watcher
+	wlClient *warcraftlogs.Client
+	queue    *outbox.Queue
+	sessions []*discordgo.Session
+}
+
+func (o *ownerCommands) handle(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	if o.ownerId == "" || i.Member == nil || i.Member.User.ID != o.ownerId {
+		slog.Warn("owner command used by another user", slog.String("server", i.GuildID), slog.String("user", interactionUserId(i)))
+		respond(s, i, i18n.T(i.Locale, "owner.only"))
+		return
+	}
+	// a stale command registration can send no subcommand
+	if len(data.Options) == 0 {
+		slog.Warn("owner command without a subcommand", slog.String("server", i.GuildID))
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	sub := data.Options[0]
+	switch sub.Name {
+	case "stats":
+		o.stats(s, i)
+	case "flags":
+		scope := storage.GlobalFlags
+		if opt, ok := optionMap(sub.Options)["server_id"]; ok {
+			scope = opt.StringValue()
+		}
+		o.flags(s, i, scope)
+	case "flag":
+		options := optionMap(sub.Options)
+		scope := storage.GlobalFlags
+		if opt, ok := options["server_id"]; ok {
+			scope = opt.StringValue()
+		}
+		o.setFlag(s, i, scope, options["name"].StringValue(), options["state"].StringValue())
+	case "version":
+		respond(s, i, "💡 "+version.String())
+	case "usage":
+		sort := "busiest"
+		if opt, ok := optionMap(sub.Options)["sort"]; ok {
+			sort = opt.StringValue()
+		}
+		o.usage(s, i, sort)
+	case "broadcast":
+		o.broadcast(s, i, optionMap(sub.Options)["message"].StringValue())
+	case "unwatch":
+		o.unwatch(s, i, optionMap(sub.Options)["server_id"].StringValue())
+	}
+}
+
+func (o *ownerCommands) stats(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	servers, err := o.store.ListServers()
+	if err != nil {
+		slog.Error("error listing servers", "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	guilds := 0
+	for _, sess := range o.sessions {
+		guilds += len(sess.State.Guilds)
+	}
+	statuses := o.w.Statuses()
+	failing := 0
+	for _, st := range statuses {
+		if st.LastError != "" {
+			failing++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(i.Locale, "owner.stats", guilds, len(servers), len(statuses), failing, o.queue.Len()))
+	b.WriteString("\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	limit, err := o.wlClient.RateLimit(ctx)
+	if err != nil {
+		slog.Error("error reading api rate limit", "error", err)
+		b.WriteString(i18n.T(i.Locale, "owner.api_usage_unavailable"))
+	} else {
+		b.WriteString(i18n.T(i.Locale, "owner.api_usage",
+			limit.PointsSpentThisHour, limit.LimitPerHour, time.Duration(limit.PointsResetIn)*time.Second))
+	}
+	respond(s, i, b.String())
+}
+
+func (o *ownerCommands) flags(s *discordgo.Session, i *discordgo.InteractionCreate, scope string) {
+	global, err := o.store.ReadFlags(storage.GlobalFlags)
+	if err != nil {
+		slog.Error("error reading global feature flags", "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	server := map[string]bool{}
+	if scope != storage.GlobalFlags {
+		if server, err = o.store.ReadFlags(scope); err != nil {
+			slog.Error("error reading feature flags", slog.String("server", scope), "error", err)
+			respond(s, i, i18n.T(i.Locale, "error.generic"))
+			return
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(i.Locale, "owner.flags_title", scope) + "\n")
+	for _, f := range features.Flags {
+		state := "off"
+		if features.Enabled(f.Name, global, server) {
+			state = "on"
+		}
+		source := "default"
+		if _, ok := server[f.Name]; ok {
+			source = "server"
+		} else if _, ok := global[f.Name]; ok {
+			source = "global"
+		}
+		b.WriteString(i18n.T(i.Locale, "owner.flag_line", f.Name, state, source, f.Description) + "\n")
+	}
+	respond(s, i, b.String())
+}
+
+func (o *ownerCommands) setFlag(s *discordgo.Session, i *discordgo.InteractionCreate, scope, name, state string) {
+	var value *bool
+	switch state {
+	case "on":
+		value = new(bool)
+		*value = true
+	case "off":
+		value = new(bool)
+	}
+	if err := o.store.SetFlag(scope, name, value); err != nil {
+		slog.Error("error saving feature flag", slog.String("scope", scope), slog.String("flag", name), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	slog.Info("feature flag changed", slog.String("scope", scope), slog.String("flag", name), slog.String("state", state))
+	respond(s, i, i18n.T(i.Locale, "owner.flag_saved", name, state, scope))
+}
+
+const usageListSize = 15
+
+func (o *ownerCommands) usage(s *discordgo.Session, i *discordgo.InteractionCreate, sort string) {
+	usage, err := o.store.ListUsage()
+	if err != nil {
+		slog.Error("error listing usage", "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	// configured servers without any recorded activity are the stalest of all
+	servers, err := o.store.ListServers()
+	if err != nil {
+		slog.Error("error listing servers", "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	for _, srv := range servers {
+		if !slices.ContainsFunc(usage, func(u storage.Usage) bool { return u.ServerId == srv.ServerId }) {
+			usage = append(usage, storage.Usage{ServerId: srv.ServerId})
+		}
+	}
+	if len(usage) == 0 {
+		respond(s, i, i18n.T(i.Locale, "owner.no_usage"))
+		return
+	}
+	switch sort {
+	case "stalest":
+		slices.SortFunc(usage, func(a, b storage.Usage) int { return a.LastActivity().Compare(b.LastActivity()) })
+	default:
+		slices.SortFunc(usage, func(a, b storage.Usage) int {
+			return (b.Updates + b.TotalCommands()) - (a.Updates + a.TotalCommands())
+		})
+	}
+
+	var b strings.Builder
+	for _, u := range usage[:min(len(usage), usageListSize)] {
+		last := i18n.T(i.Locale, "owner.never")
+		if t := u.LastActivity(); !t.IsZero() {
+			last = discordTime(t, 'R')
+		}
+		b.WriteString(i18n.T(i.Locale, "owner.usage_line", u.ServerId, u.Updates, u.TotalCommands(), last) + "\n")
+	}
+	respond(s, i, b.String())
+}
+
+func (o *ownerCommands) broadcast(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+	servers, err := o.store.ListServers()
+	if err != nil {
+		slog.Error("error listing servers", "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	for _, srv := range servers {
+		channelId := srv.ChannelId
+		// the queue keeps only the latest entry per key, every broadcast has keys of its own
+		o.queue.Enqueue("broadcast:"+i.ID+":"+channelId, func() {
+			if _, err := s.ChannelMessageSend(channelId, "📢 "+message); err != nil {
+				slog.Error("error sending announcement", slog.String("server", srv.ServerId), slog.String("channel", channelId), "error", err)
+			}
+		})
+	}
+	slog.Info("announcement queued", slog.Int("channels", len(servers)))
+	respond(s, i, i18n.T(i.Locale, "owner.broadcast_queued", len(servers)))
+}
+
+func (o *ownerCommands) unwatch(s *discordgo.Session, i *discordgo.InteractionCreate, serverId string) {
+	if _, ok := o.w.Status(serverId); !ok {
+		respond(s, i, i18n.T(i.Locale, "owner.not_watched", serverId))
+		return
+	}
+	slog.Warn("watcher stopped by owner", slog.String("server", serverId))
+	o.w.Unwatch(serverId)
+	respond(s, i, i18n.T(i.Locale, "owner.unwatched", serverId))
+}
+
+func interactionUserId(i *discordgo.InteractionCreate) string {
+	if i.Member != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// alertOperator posts the message to the ops channel, or sends it to the owner directly when no channel is configured.
+func alertOperator(s *discordgo.Session, ownerId, opsChannelId, message string) {
+	channelId := opsChannelId
+	if channelId == "" {
+		if ownerId == "" {
+			slog.Warn("no owner or ops channel configured for alerts", slog.String("alert", message))
+			return
+		}
+		dm, err := s.UserChannelCreate(ownerId)
+		if err != nil {
+			slog.Error("error opening direct message to owner", "error", err)
+			return
+		}
+		channelId = dm.ID
+	}
+	if _, err := s.ChannelMessageSend(channelId, message); err != nil {
+		slog.Error("error sending operator alert", slog.String("channel", channelId), "error", err)
+	}
+}
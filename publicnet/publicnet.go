@@ -0,0 +1,66 @@
+// Package publicnet keeps requests to urls set by server admins away from the network the bot runs in.
+package publicnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrPrivateAddress = errors.New("address is not public")
+
+// CheckHost resolves the host and fails if any of its addresses is not public.
+// Clients from NewClient check the addresses again when connecting, since the name may resolve differently by then.
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublic(addr) {
+			return fmt.Errorf("%v resolves to %v: %w", host, addr.Unmap(), ErrPrivateAddress)
+		}
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, cloud providers use it for internal services as well.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsMulticast() && !addr.IsUnspecified() && !sharedAddressSpace.Contains(addr)
+}
+
+// publicOnly refuses connections to addresses that are not public, it runs after name resolution for every dial.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublic(addrPort.Addr()) {
+		return fmt.Errorf("%v: %w", addrPort.Addr(), ErrPrivateAddress)
+	}
+	return nil
+}
+
+// NewClient returns a client that only connects to public addresses, also after redirects,
+// and ignores proxy settings, which would hide the address from the check.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicOnly}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,135 @@
+package raiderio
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+const guildProfileURL = "https://raider.io/api/v1/guilds/profile"
+
+type Rank struct {
+	World  int `json:"world"`
+	Region int `json:"region"`
+	Realm  int `json:"realm"`
+}
+
+type raidProgression struct {
+	Summary            string `json:"summary"`
+	TotalBosses        int    `json:"total_bosses"`
+	NormalBossesKilled int    `json:"normal_bosses_killed"`
+	HeroicBossesKilled int    `json:"heroic_bosses_killed"`
+	MythicBossesKilled int    `json:"mythic_bosses_killed"`
+}
+
+type GuildProfile struct {
+	Name         string                     `json:"name"`
+	Realm        string                     `json:"realm"`
+	Region       string                     `json:"region"`
+	ProfileURL   string                     `json:"profile_url"`
+	RaidProgress map[string]raidProgression `json:"raid_progression"`
+	RaidRankings map[string]map[string]Rank `json:"raid_rankings"`
+}
+
+// RaidProgress is the progression of a guild in a single raid, ranked on the hardest difficulty with a kill.
+type RaidProgress struct {
+	Raid        string
+	Summary     string
+	TotalBosses int
+	Difficulty  string
+	Rank        Rank
+}
+
+// Raids returns the progression in every raid the guild has killed a boss in, ordered by raid.
+func (p GuildProfile) Raids() []RaidProgress {
+	var raids []RaidProgress
+	for slug := range p.RaidProgress {
+		if rp, ok := p.Raid(slug); ok {
+			raids = append(raids, rp)
+		}
+	}
+	sort.Slice(raids, func(i, j int) bool { return raids[i].Raid < raids[j].Raid })
+	return raids
+}
+
+// Raid returns the progression in the raid, the slug may also be the raid name as shown by Warcraft Logs.
+func (p GuildProfile) Raid(raid string) (RaidProgress, bool) {
+	slug := Slug(raid)
+	prog, ok := p.RaidProgress[slug]
+	if !ok || prog.NormalBossesKilled+prog.HeroicBossesKilled+prog.MythicBossesKilled == 0 {
+		return RaidProgress{}, false
+	}
+	difficulty := "normal"
+	switch {
+	case prog.MythicBossesKilled > 0:
+		difficulty = "mythic"
+	case prog.HeroicBossesKilled > 0:
+		difficulty = "heroic"
+	}
+	return RaidProgress{
+		Raid:        slug,
+		Summary:     prog.Summary,
+		TotalBosses: prog.TotalBosses,
+		Difficulty:  difficulty,
+		Rank:        p.RaidRankings[slug][difficulty],
+	}, true
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slug converts a raid or realm name to the form used by Raider.IO, "Nerub-ar Palace" becomes "nerubar-palace".
+func Slug(name string) string {
+	s := strings.ToLower(name)
+	s = strings.NewReplacer("'", "", "’", "", "-", "").Replace(s)
+	return strings.Trim(nonSlug.ReplaceAllString(s, "-"), "-")
+}
+
+type Client struct {
+	resty *resty.Client
+	cache *ttlcache.Cache[string, GuildProfile]
+}
+
+func NewClient() *Client {
+	cache := ttlcache.New[string, GuildProfile](
+		ttlcache.WithTTL[string, GuildProfile](15 * time.Minute),
+	)
+	go cache.Start()
+	return &Client{
+		resty: resty.New().SetTimeout(10 * time.Second),
+		cache: cache,
+	}
+}
+
+// GuildProfile returns the raid progression and rankings of the guild, profiles are cached for 15 minutes.
+func (c *Client) GuildProfile(ctx context.Context, region, realm, name string) (GuildProfile, error) {
+	key := strings.ToLower(region + "/" + realm + "/" + name)
+	if item := c.cache.Get(key); item != nil {
+		return item.Value(), nil
+	}
+
+	var profile GuildProfile
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"region": strings.ToLower(region),
+			"realm":  realm,
+			"name":   name,
+			"fields": "raid_progression,raid_rankings",
+		}).
+		SetResult(&profile).
+		Get(guildProfileURL)
+	if err != nil {
+		return GuildProfile{}, err
+	}
+	if resp.IsError() {
+		return GuildProfile{}, fmt.Errorf("raider.io guild profile: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	c.cache.Set(key, profile, ttlcache.DefaultTTL)
+	return profile, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"bot/i18n"
+	"bot/storage"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// scheduleHorizon is how far ahead raids get a discord scheduled event.
+const scheduleHorizon = 7 * 24 * time.Hour
+
+// syncSchedule creates, moves and cancels the discord scheduled events of the server to match its calendar.
+func syncSchedule(dg *discordgo.Session, store *storage.Store, se watcher.ScheduleEvent) {
+	serverId := se.Server.ServerId
+	schedule, err := store.ReadSchedule(serverId)
+	if err != nil {
+		slog.Error("error reading scheduled events", slog.String("server", serverId), "error", err)
+		return
+	}
+
+	locale := discordgo.Locale(se.Server.Locale)
+	now := time.Now()
+	upcoming := make(map[string]bool)
+	changed := false
+	for _, e := range se.Events {
+		if !e.Start.After(now) || e.Start.Sub(now) > scheduleHorizon {
+			continue
+		}
+		upcoming[e.UID] = true
+		name := e.Summary
+		if name == "" {
+			name = i18n.T(locale, "schedule.default_name")
+		}
+		want := storage.ScheduledEvent{Name: truncate(name, 100), Start: e.Start, End: e.End}
+		current, ok := schedule[e.UID]
+		if ok && current.Name == want.Name && current.Start.Equal(want.Start) && current.End.Equal(want.End) {
+			continue
+		}
+
+		params := &discordgo.GuildScheduledEventParams{
+			Name:               want.Name,
+			Description:        i18n.T(locale, "schedule.description", siteName(se.Server)),
+			ScheduledStartTime: &want.Start,
+			ScheduledEndTime:   &want.End,
+			PrivacyLevel:       discordgo.GuildScheduledEventPrivacyLevelGuildOnly,
+			EntityType:         discordgo.GuildScheduledEventEntityTypeExternal,
+			EntityMetadata:     &discordgo.GuildScheduledEventEntityMetadata{Location: guildURL(se.Server)},
+		}
+		var event *discordgo.GuildScheduledEvent
+		if ok {
+			event, err = dg.GuildScheduledEventEdit(serverId, current.EventId, params)
+			// the event was deleted in discord, it is created again
+			var restErr *discordgo.RESTError
+			if errors.As(err, &restErr) && restErr.Response.StatusCode == http.StatusNotFound {
+				event, err = dg.GuildScheduledEventCreate(serverId, params)
+			}
+		} else {
+			event, err = dg.GuildScheduledEventCreate(serverId, params)
+		}
+		if err != nil {
+			slog.Error("error saving scheduled event", slog.String("server", serverId), slog.String("event", e.UID), "error", err)
+			continue
+		}
+		want.EventId = event.ID
+		schedule[e.UID] = want
+		changed = true
+	}
+
+	for uid, ev := range schedule {
+		switch {
+		case now.Sub(ev.End) > 24*time.Hour:
+		case ev.Start.After(now) && !upcoming[uid]:
+			// the raid was removed from the calendar
+			if err := dg.GuildScheduledEventDelete(serverId, ev.EventId); err != nil {
+				slog.Error("error deleting scheduled event", slog.String("server", serverId), slog.String("event", uid), "error", err)
+			}
+		default:
+			continue
+		}
+		delete(schedule, uid)
+		changed = true
+	}
+
+	if changed {
+		if err := store.SaveSchedule(serverId, schedule); err != nil {
+			slog.Error("error saving scheduled events", slog.String("server", serverId), "error", err)
+		}
+	}
+}
+
+// scheduledRaid returns the scheduled event the report was uploaded for, reports may start an hour early.
+func scheduledRaid(store *storage.Store, se watcher.StatsEvent) (storage.ScheduledEvent, bool) {
+	schedule, err := store.ReadSchedule(se.Server.ServerId)
+	if err != nil {
+		slog.Error("error reading scheduled events", slog.String("server", se.Server.ServerId), "error", err)
+		return storage.ScheduledEvent{}, false
+	}
+	for _, ev := range schedule {
+		if se.StartedAt.After(ev.Start.Add(-time.Hour)) && se.StartedAt.Before(ev.End) {
+			return ev, true
+		}
+	}
+	return storage.ScheduledEvent{}, false
+}
+
+// attachSummary starts a thread named after the scheduled raid on the summary message and links the event in it.
+func attachSummary(dg *discordgo.Session, server storage.Server, summary *discordgo.Message, ev storage.ScheduledEvent) error {
+	thread, err := dg.MessageThreadStart(summary.ChannelID, summary.ID, ev.Name, 1440)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("https://discord.com/events/%v/%v", server.ServerId, ev.EventId)
+	_, err = dg.ChannelMessageSend(thread.ID, i18n.T(discordgo.Locale(server.Locale), "schedule.summary", link))
+	return err
+}
+
+func guildURL(server storage.Server) string {
+	if site, ok := warcraftlogs.SiteById(server.Site); ok {
+		return site.GuildURL(server.WlGuildId)
+	}
+	return warcraftlogs.Warcraft.GuildURL(server.WlGuildId)
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bot/calendar"
+	"bot/i18n"
+	"bot/sheets"
+	"bot/storage"
+	"bot/twitch"
+	"bot/warcraftlogs"
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// serverCommands handles the slash commands of servers, the owner command is handed to ownerCommands.
+type serverCommands struct {
+	config   Config
+	store    *storage.Store
+	w        *watcher.Watcher
+	owner    *ownerCommands
+	calendar *calendar.Client
+	mirrors  *mirrors
+	sheets   *sheets.Client
+	twitch   *twitch.Client
+	guilds   *guildLookup
+	login    *battleNetLogin
+	feedback *ttlcache.Cache[string, struct{}]
+	// backfills holds the servers with a running history backfill
+	backfills sync.Map
+}
+
+func (c *serverCommands) handle(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	switch data.Name {
+	case "set-config":
+		c.setConfig(s, i, data)
+	case "get-config":
+		c.showConfig(s, i)
+	case "export-config":
+		c.exportSettings(s, i)
+	case "import-config":
+		c.importSettings(s, i, data)
+	case "embed-settings":
+		c.embedSettings(s, i, data)
+	case "owner":
+		c.owner.handle(s, i, data)
+	case "webhook":
+		c.webhook(s, i, data)
+	case "events":
+		c.events(s, i, data)
+	case "stats-api":
+		c.statsAPI(s, i, data)
+	case "time-series":
+		c.timeSeries(s, i, data)
+	case "sheet":
+		c.sheet(s, i, data)
+	case "calendar":
+		c.calendarFeed(s, i, data)
+	case "mirror":
+		c.mirror(s, i, data)
+	case "twitch":
+		c.streamer(s, i, data)
+	case "scheduled-events":
+		c.scheduledEvents(s, i, data)
+	case "raid-threads":
+		c.raidThreads(s, i, data)
+	case "backfill":
+		c.backfill(s, i)
+	case "link-replies":
+		c.linkReplies(s, i, data)
+	case "claim-verification":
+		c.claimVerification(s, i, data)
+	case "progress":
+		c.progress(s, i)
+	case "leaderboard":
+		c.leaderboard(s, i)
+	case "verify":
+		c.verify(s, i)
+	case "claim":
+		c.claim(s, i, data)
+	case "unclaim":
+		c.unclaim(s, i, data)
+	case "feedback":
+		c.sendFeedback(s, i, data)
+	default:
+		slog.Warn("unknown command, should remove it", slog.String("server", i.GuildID), slog.String("command", data.Name))
+		respond(s, i, i18n.T(i.Locale, "error.unknown_command"))
+		removeCommand(s, i.GuildID, data)
+	}
+}
+
+var errNotConfigured = errors.New("server is not configured")
+
+// commandError refuses a command, it is answered with the message of the key.
+type commandError struct {
+	key  string
+	args []any
+}
+
+func (e commandError) Error() string {
+	return e.key
+}
+
+func refuse(key string, args ...any) error {
+	return commandError{key: key, args: args}
+}
+
+// respondError answers a command that failed with err, refusals with their message and anything else
+// with the generic error.
+func respondError(s *discordgo.Session, i *discordgo.InteractionCreate, err error) {
+	var refusal commandError
+	switch {
+	case errors.As(err, &refusal):
+		respond(s, i, i18n.T(i.Locale, refusal.key, refusal.args...))
+	case errors.Is(err, errNotConfigured):
+		respond(s, i, i18n.T(i.Locale, "error.not_configured"))
+	default:
+		slog.Error("error updating configuration", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+	}
+}
+
+// readServer returns the configuration of the server of the interaction, it answers the interaction itself
+// when there is none.
+func (c *serverCommands) readServer(s *discordgo.Session, i *discordgo.InteractionCreate) (*storage.Server, bool) {
+	server, err := c.store.ReadServer(i.GuildID)
+	if err != nil {
+		slog.Error("error reading configuration", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return nil, false
+	}
+	if server == nil {
+		respond(s, i, i18n.T(i.Locale, "error.not_configured"))
+		return nil, false
+	}
+	return server, true
+}
+
+// updateServer changes the configuration of the server with fn and saves it, an error of fn leaves it unchanged.
+func (c *serverCommands) updateServer(serverId string, fn func(server *storage.Server) error) (storage.Server, error) {
+	previous, err := c.store.ReadServer(serverId)
+	if err != nil {
+		return storage.Server{}, err
+	}
+	if previous == nil {
+		return storage.Server{}, errNotConfigured
+	}
+	server := *previous
+	// fn may change the maps and slices in place, previous has to keep the settings the watcher polls with
+	server.Mirrors = maps.Clone(previous.Mirrors)
+	server.Streamers = maps.Clone(previous.Streamers)
+	server.LinkChannels = slices.Clone(previous.LinkChannels)
+	server.Medals = slices.Clone(previous.Medals)
+	if err := fn(&server); err != nil {
+		return server, err
+	}
+	return server, c.saveServer(previous, server)
+}
+
+// saveServer stores the configuration and hands it to the watcher. Only a new guild, site or wipe cutoff restarts
+// the watch loop, which forgets the reports it has seen and loads them all again, other settings are used from the
+// next poll on and a changed calendar or poll interval is polled right away.
+func (c *serverCommands) saveServer(previous *storage.Server, server storage.Server) error {
+	if err := c.store.SaveServer(server); err != nil {
+		return err
+	}
+	if previous == nil || previous.WlGuildId != server.WlGuildId || previous.Site != server.Site || previous.WipeCutoff != server.WipeCutoff {
+		c.w.Unwatch(server.ServerId)
+		c.w.Watch(server)
+		return nil
+	}
+	if !c.w.Update(server) {
+		c.w.Watch(server)
+		return nil
+	}
+	if previous.PollInterval != server.PollInterval || previous.CalendarURL != server.CalendarURL || previous.ScheduledEvents != server.ScheduledEvents {
+		c.w.Refresh(server.ServerId)
+	}
+	return nil
+}
+
+func (c *serverCommands) setConfig(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	options := optionMap(data.Options)
+	channel := options["channel"].ChannelValue(s)
+	channelId := channel.ID
+	wlGuildId := options["guild_id"].IntValue()
+	wipeCutoff := options["wipe_cutoff"].IntValue()
+	server := storage.Server{ServerId: i.GuildID}
+	existing, _ := c.store.ReadServer(i.GuildID)
+	if existing != nil {
+		server = *existing
+	}
+	if server.WebhookId != "" && server.ChannelId != channelId {
+		if _, err := s.WebhookEdit(server.WebhookId, "", "", channelId); err != nil {
+			slog.Warn("error moving webhook, falling back to bot messages", slog.String("server", i.GuildID), "error", err)
+			server.WebhookId, server.WebhookToken = "", ""
+		}
+	}
+	server.ChannelId = channelId
+	server.WlGuildId = wlGuildId
+	server.WipeCutoff = wipeCutoff
+	if opt, ok := options["site"]; ok {
+		server.Site = opt.StringValue()
+		if server.Site == warcraftlogs.Warcraft.Id {
+			server.Site = ""
+		}
+	}
+	if _, err := c.w.Client(server); err != nil {
+		respond(s, i, i18n.T(i.Locale, "config.site_unavailable"))
+		return
+	}
+	if opt, ok := options["language"]; ok {
+		server.Locale = opt.StringValue()
+	} else if server.Locale == "" {
+		server.Locale = string(guildLocale(i))
+	}
+	if opt, ok := options["poll_interval"]; ok {
+		server.PollInterval = opt.IntValue()
+	}
+	// forum channels only take posts, every raid night gets its own
+	forum := channel.Type == discordgo.ChannelTypeGuildForum
+	if forum {
+		server.RaidThreads = true
+	}
+	if err := c.saveServer(existing, server); err != nil {
+		slog.Error("error saving configuration", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	slog.Info("bot is configured", slog.String("server", i.GuildID), slog.String("channelId", channelId), slog.Int64("wlGuildId", wlGuildId))
+	if forum {
+		respond(s, i, i18n.T(i.Locale, "config.saved")+"\n"+i18n.T(i.Locale, "config.forum"))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "config.saved"))
+	}
+}
+
+func (c *serverCommands) showConfig(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	server, ok := c.readServer(s, i)
+	if !ok {
+		return
+	}
+	respond(s, i, i18n.T(i.Locale, "config.show", server.ChannelId, server.WlGuildId, server.WipeCutoff, watcher.PollInterval(*server), i18n.T(discordgo.Locale(server.Locale), "language.name")))
+}
+
+func (c *serverCommands) exportSettings(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	server, ok := c.readServer(s, i)
+	if !ok {
+		return
+	}
+	out, err := exportConfig(c.store, *server)
+	if err != nil {
+		slog.Error("error exporting configuration", slog.String("server", i.GuildID), "error", err)
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	respondFile(s, i, i18n.T(i.Locale, "config.exported"), "warcraftlogs-config.json", out)
+}
+
+func (c *serverCommands) importSettings(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	var attachment *discordgo.MessageAttachment
+	if opt, ok := optionMap(data.Options)["file"]; ok && data.Resolved != nil {
+		id, _ := opt.Value.(string)
+		attachment = data.Resolved.Attachments[id]
+	}
+	if attachment == nil {
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	if attachment.Size > maxConfigFileSize {
+		respond(s, i, i18n.T(i.Locale, "config.import_too_large"))
+		return
+	}
+	// downloading the file and checking the members of the claims can take longer than an interaction may
+	deferResponse(s, i)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	raw, err := fetchAttachment(ctx, attachment.URL)
+	if errors.Is(err, errConfigTooLarge) {
+		editResponse(s, i, i18n.T(i.Locale, "config.import_too_large"))
+		return
+	}
+	if err != nil {
+		slog.Error("error downloading configuration", slog.String("server", i.GuildID), "error", err)
+		editResponse(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	cf, err := parseConfig(raw)
+	if err != nil {
+		editResponse(s, i, i18n.T(i.Locale, "config.import_invalid", err))
+		return
+	}
+	server := cf.Server
+	server.ServerId = i.GuildID
+	existing, _ := c.store.ReadServer(i.GuildID)
+	keepServerBound(&server, existing)
+	// the exported channel is only kept when the file comes from this server
+	if ch, err := s.State.Channel(server.ChannelId); err != nil || ch.GuildID != i.GuildID {
+		server.ChannelId = i.ChannelID
+		if existing != nil {
+			server.ChannelId = existing.ChannelId
+		}
+	}
+	if server.WebhookId != "" && (existing == nil || existing.ChannelId != server.ChannelId) {
+		if _, err := s.WebhookEdit(server.WebhookId, "", "", server.ChannelId); err != nil {
+			slog.Warn("error moving webhook, falling back to bot messages", slog.String("server", i.GuildID), "error", err)
+			server.WebhookId, server.WebhookToken = "", ""
+		}
+	}
+	if err := c.saveServer(existing, server); err != nil {
+		slog.Error("error saving configuration", slog.String("server", i.GuildID), "error", err)
+		editResponse(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	imported := 0
+	for character, userId := range cf.Claims {
+		// claims of files from other servers could name anyone, only members of this server are taken over
+		if !isMember(ctx, s, i.GuildID, userId) {
+			continue
+		}
+		owner, err := c.store.SaveClaim(i.GuildID, character, userId)
+		if err != nil {
+			slog.Error("error importing claim", slog.String("server", i.GuildID), "error", err)
+			continue
+		}
+		if owner == userId {
+			imported++
+		}
+	}
+	slog.Info("configuration imported", slog.String("server", i.GuildID), slog.String("channelId", server.ChannelId), slog.Int64("wlGuildId", server.WlGuildId), slog.Int("claims", imported))
+	editResponse(s, i, i18n.T(i.Locale, "config.imported", server.ChannelId, server.WlGuildId, imported))
+}
+
+func (c *serverCommands) embedSettings(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	options := optionMap(data.Options)
+	server, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if opt, ok := options["mode"]; ok {
+			server.EmbedMode = opt.StringValue()
+		}
+		if opt, ok := options["theme"]; ok {
+			server.Theme = opt.StringValue()
+		}
+		if opt, ok := options["layout"]; ok {
+			server.Layout = opt.StringValue()
+		}
+		if opt, ok := options["medals"]; ok {
+			medals := strings.Fields(opt.StringValue())
+			if len(medals) != 3 && !(len(medals) == 1 && medals[0] == "default") {
+				return refuse("settings.invalid_medals")
+			}
+			if len(medals) != 3 {
+				medals = nil
+			}
+			server.Medals = medals
+		}
+		if opt, ok := options["mentions"]; ok {
+			server.MentionClaims = opt.BoolValue()
+		}
+		if opt, ok := options["spoilers"]; ok {
+			server.SpoilerMode = opt.StringValue()
+			if server.SpoilerMode == "off" {
+				server.SpoilerMode = storage.SpoilersOff
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("embed settings updated", slog.String("server", i.GuildID), slog.String("mode", server.EmbedMode), slog.String("theme", server.Theme))
+	respond(s, i, i18n.T(i.Locale, "settings.saved", embedModeOrDefault(server.EmbedMode), themeOrDefault(server.Theme), layoutOrDefault(server.Layout), medalsOrDefault(server.Medals), spoilerModeOrDefault(server.SpoilerMode), server.MentionClaims))
+}
+
+func (c *serverCommands) webhook(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	options := optionMap(data.Options)
+	enabled := options["enabled"].BoolValue()
+	_, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if opt, ok := options["name"]; ok {
+			server.WebhookName = opt.StringValue()
+		}
+		if opt, ok := options["avatar_url"]; ok {
+			server.WebhookAvatar = opt.StringValue()
+		}
+		switch {
+		case enabled && server.WebhookId == "":
+			hook, err := s.WebhookCreate(server.ChannelId, "Warcraft Logs", "")
+			if err != nil {
+				slog.Error("error creating webhook", slog.String("server", i.GuildID), slog.String("channel", server.ChannelId), "error", err)
+				return refuse("webhook.create_failed")
+			}
+			server.WebhookId, server.WebhookToken = hook.ID, hook.Token
+		case !enabled && server.WebhookId != "":
+			if err := s.WebhookDelete(server.WebhookId); err != nil {
+				slog.Warn("error deleting webhook", slog.String("server", i.GuildID), "error", err)
+			}
+			server.WebhookId, server.WebhookToken = "", ""
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("webhook delivery updated", slog.String("server", i.GuildID), slog.Bool("enabled", enabled))
+	if enabled {
+		respond(s, i, i18n.T(i.Locale, "webhook.enabled"))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "webhook.disabled"))
+	}
+}
+
+func (c *serverCommands) raidThreads(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	enabled := optionMap(data.Options)["enabled"].BoolValue()
+	_, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if !enabled && isForum(s, server.ChannelId) {
+			return refuse("threads.forum_required")
+		}
+		server.RaidThreads = enabled
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("raid threads updated", slog.String("server", i.GuildID), slog.Bool("enabled", enabled))
+	if enabled {
+		respond(s, i, i18n.T(i.Locale, "threads.enabled"))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "threads.disabled"))
+	}
+}
+
+func (c *serverCommands) backfill(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	server, ok := c.readServer(s, i)
+	if !ok {
+		return
+	}
+	client, err := c.w.Client(*server)
+	if err != nil {
+		respond(s, i, i18n.T(i.Locale, "error.generic"))
+		return
+	}
+	if _, running := c.backfills.LoadOrStore(i.GuildID, true); running {
+		respond(s, i, i18n.T(i.Locale, "backfill.running"))
+		return
+	}
+	respond(s, i, i18n.T(i.Locale, "backfill.started"))
+	go func() {
+		defer c.backfills.Delete(i.GuildID)
+		// the interaction token expires after 15 minutes, larger backfills only log their result
+		ctx, cancel := context.WithTimeout(context.Background(), 14*time.Minute)
+		defer cancel()
+		imported, err := backfillHistory(ctx, c.store, client, *server)
+		content := i18n.N(i.Locale, "backfill.done", imported)
+		if err != nil {
+			slog.Error("error backfilling history", slog.String("server", i.GuildID), slog.Int("imported", imported), "error", err)
+			content = i18n.T(i.Locale, "backfill.failed", imported)
+		} else {
+			slog.Info("history backfilled", slog.String("server", i.GuildID), slog.Int("imported", imported))
+		}
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			slog.Warn("error reporting backfill result", slog.String("server", i.GuildID), "error", err)
+		}
+	}()
+}
+
+func (c *serverCommands) claimVerification(s *discordgo.Session, i *discordgo.InteractionCreate, data discordgo.ApplicationCommandInteractionData) {
+	required := optionMap(data.Options)["required"].BoolValue()
+	_, err := c.updateServer(i.GuildID, func(server *storage.Server) error {
+		if required && (!c.login.enabled() || !isWarcraft(*server)) {
+			return refuse("verify.unavailable")
+		}
+		server.VerifiedClaims = required
+		return nil
+	})
+	if err != nil {
+		respondError(s, i, err)
+		return
+	}
+	slog.Info("claim verification updated", slog.String("server", i.GuildID), slog.Bool("required", required))
+	if required {
+		respond(s, i, i18n.T(i.Locale, "verify.required"))
+	} else {
+		respond(s, i, i18n.T(i.Locale, "verify.optional"))
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ShardConfig struct {
+	ShardId    int    `envconfig:"SHARD_ID" yaml:"shard_id" toml:"shard_id"`
+	ShardCount string `envconfig:"SHARD_COUNT" yaml:"shard_count" toml:"shard_count"`
+}
+
+// newSessions creates the gateway sessions served by this process: either the single configured shard,
+// or every shard recommended by discord when the shard count is auto.
+func newSessions(token string, cfg ShardConfig) ([]*discordgo.Session, error) {
+	if cfg.ShardCount != "auto" {
+		count, err := strconv.Atoi(cfg.ShardCount)
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("invalid shard count %q", cfg.ShardCount)
+		}
+		if cfg.ShardId < 0 || cfg.ShardId >= count {
+			return nil, fmt.Errorf("shard id %d is out of range for %d shards", cfg.ShardId, count)
+		}
+		s, err := discordgo.New(token)
+		if err != nil {
+			return nil, err
+		}
+		s.ShardID = cfg.ShardId
+		s.ShardCount = count
+		return []*discordgo.Session{s}, nil
+	}
+
+	probe, err := discordgo.New(token)
+	if err != nil {
+		return nil, err
+	}
+	gateway, err := probe.GatewayBot()
+	if err != nil {
+		return nil, fmt.Errorf("error requesting recommended shard count: %w", err)
+	}
+	count := max(gateway.Shards, 1)
+	slog.Info("using recommended shard count", slog.Int("shards", count))
+
+	sessions := make([]*discordgo.Session, count)
+	for i := range sessions {
+		s, err := discordgo.New(token)
+		if err != nil {
+			return nil, err
+		}
+		s.ShardID = i
+		s.ShardCount = count
+		sessions[i] = s
+	}
+	return sessions, nil
+}
+
+// openSessions connects shards one by one, discord allows a single identify per 5 seconds by default.
+func openSessions(sessions []*discordgo.Session) error {
+	for i, s := range sessions {
+		if i > 0 {
+			time.Sleep(5 * time.Second)
+		}
+		if err := s.Open(); err != nil {
+			return fmt.Errorf("error opening shard %d: %w", s.ShardID, err)
+		}
+		slog.Info("shard is connected", slog.Int("shard", s.ShardID), slog.Int("shards", s.ShardCount))
+	}
+	return nil
+}
+
+func closeSessions(sessions []*discordgo.Session) {
+	for _, s := range sessions {
+		if err := s.Close(); err != nil {
+			slog.Error("error closing shard", slog.Int("shard", s.ShardID), "error", err)
+		}
+	}
+}
+
+// guildShard returns the shard receiving gateway events of the guild.
+func guildShard(guildId string, shardCount int) int {
+	id, err := strconv.ParseUint(guildId, 10, 64)
+	if err != nil || shardCount <= 1 {
+		return 0
+	}
+	return int((id >> 22) % uint64(shardCount))
+}
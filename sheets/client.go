@@ -0,0 +1,233 @@
+package sheets
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	apiURL = "https://sheets.googleapis.com/v4/spreadsheets/"
+	scope  = "https://www.googleapis.com/auth/spreadsheets"
+)
+
+// serviceAccount is the part of a Google service account key file the client needs.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type tokenResp struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Client appends rows to spreadsheets shared with a service account.
+type Client struct {
+	email    string
+	key      *rsa.PrivateKey
+	tokenURI string
+
+	resty *resty.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewClient reads a service account key file as downloaded from the Google Cloud console.
+func NewClient(credentialsFile string) (*Client, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	var sa serviceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("error parsing credentials file: %w", err)
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, errors.New("credentials file has no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an rsa key")
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &Client{
+		email:    sa.ClientEmail,
+		key:      key,
+		tokenURI: sa.TokenURI,
+		resty:    resty.New().SetTimeout(10 * time.Second),
+	}, nil
+}
+
+// Email is the service account address spreadsheets have to be shared with.
+func (c *Client) Email() string {
+	return c.email
+}
+
+// assertion builds the signed JWT exchanged for an access token.
+func (c *Client) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   c.email,
+		"scope": scope,
+		"aud":   c.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Add(time.Minute).Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	assertion, err := c.assertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	var tr tokenResp
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetFormData(map[string]string{
+			"grant_type": "urn:ietf:params:oauth:grant-type:jwt-bearer",
+			"assertion":  assertion,
+		}).
+		SetResult(&tr).
+		Post(c.tokenURI)
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("google oauth token failed: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	c.token = tr.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// Title returns the title of the spreadsheet, it fails when the spreadsheet is not shared with the service account.
+func (c *Client) Title(ctx context.Context, spreadsheetId string) (string, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Properties struct {
+			Title string `json:"title"`
+		} `json:"properties"`
+	}
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		SetQueryParam("fields", "properties.title").
+		SetResult(&out).
+		Get(apiURL + url.PathEscape(spreadsheetId))
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("google sheets spreadsheet: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	return out.Properties.Title, nil
+}
+
+// Empty reports whether the first cell of the tab is empty, the first tab is used when tab is empty.
+func (c *Client) Empty(ctx context.Context, spreadsheetId, tab string) (bool, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return false, err
+	}
+	var out struct {
+		Values [][]any `json:"values"`
+	}
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		SetResult(&out).
+		Get(apiURL + url.PathEscape(spreadsheetId) + "/values/" + url.PathEscape(tabRange(tab, "A1:A1")))
+	if err != nil {
+		return false, err
+	}
+	if resp.IsError() {
+		return false, fmt.Errorf("google sheets values: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	return len(out.Values) == 0, nil
+}
+
+// Append adds the rows after the last row of the tab, the first tab is used when tab is empty. Values are stored
+// as they are, never parsed, so text from reports can't become formulas.
+func (c *Client) Append(ctx context.Context, spreadsheetId, tab string, rows [][]any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	rng := tabRange(tab, "A1")
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		SetQueryParams(map[string]string{
+			"valueInputOption": "RAW",
+			"insertDataOption": "INSERT_ROWS",
+		}).
+		SetBody(map[string]any{"values": rows}).
+		Post(apiURL + url.PathEscape(spreadsheetId) + "/values/" + url.PathEscape(rng) + ":append")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("google sheets append: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	return nil
+}
+
+func tabRange(tab, cells string) string {
+	if tab == "" {
+		return cells
+	}
+	return "'" + strings.ReplaceAll(tab, "'", "''") + "'!" + cells
+}
+
+// SpreadsheetId extracts the id from a spreadsheet url, anything else is returned as is.
+func SpreadsheetId(s string) string {
+	s = strings.TrimSpace(s)
+	if _, rest, ok := strings.Cut(s, "/spreadsheets/d/"); ok {
+		id, _, _ := strings.Cut(rest, "/")
+		return id
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bot/events"
+	"bot/storage"
+	"bot/watcher"
+)
+
+// latestStats keeps the last stats of every server for the stats api and the overlay, they are lost on restart
+// and served again from the next update.
+type latestStats struct {
+	mu     sync.RWMutex
+	stats  map[string]watcher.StatsEvent
+	subs   map[string]map[chan watcher.StatsEvent]struct{}
+	closed bool
+}
+
+func newLatestStats() *latestStats {
+	return &latestStats{
+		stats: make(map[string]watcher.StatsEvent),
+		subs:  make(map[string]map[chan watcher.StatsEvent]struct{}),
+	}
+}
+
+func (l *latestStats) set(se watcher.StatsEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats[se.Server.ServerId] = se
+	for ch := range l.subs[se.Server.ServerId] {
+		// slow subscribers only get the newest stats
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- se
+	}
+}
+
+// subscribe returns a channel receiving the stats of every update of the server, it is closed on shutdown.
+func (l *latestStats) subscribe(serverId string) (<-chan watcher.StatsEvent, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch := make(chan watcher.StatsEvent, 1)
+	if l.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if l.subs[serverId] == nil {
+		l.subs[serverId] = make(map[chan watcher.StatsEvent]struct{})
+	}
+	l.subs[serverId][ch] = struct{}{}
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subs[serverId][ch]; ok {
+			delete(l.subs[serverId], ch)
+			close(ch)
+		}
+	}
+}
+
+// close ends all subscriptions, open event streams would otherwise hold up the http server shutdown.
+func (l *latestStats) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	for serverId, subs := range l.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(l.subs, serverId)
+	}
+}
+
+func (l *latestStats) get(serverId string) (watcher.StatsEvent, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	se, ok := l.stats[serverId]
+	return se, ok
+}
+
+type apiLatest struct {
+	ServerId    string        `json:"server_id"`
+	NextRefresh time.Time     `json:"next_refresh"`
+	Report      events.Report `json:"report"`
+}
+
+type statsAPI struct {
+	store  *storage.Store
+	latest *latestStats
+}
+
+// registerStatsAPI serves the latest stats of servers with an api token. Guild websites call it from the browser,
+// so any origin is allowed and the token may also be passed as the token query parameter.
+func registerStatsAPI(mux *http.ServeMux, store *storage.Store, latest *latestStats) *statsAPI {
+	api := &statsAPI{store: store, latest: latest}
+	mux.HandleFunc("GET /api/servers/{id}/latest", api.latestReport)
+	mux.HandleFunc("OPTIONS /api/servers/{id}/latest", api.preflight)
+	return api
+}
+
+func (api *statsAPI) latestReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	serverId := r.PathValue("id")
+	if !api.authorized(r, serverId) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	se, ok := api.latest.get(serverId)
+	if !ok {
+		http.Error(w, "no reports yet", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, http.StatusOK, apiLatest{ServerId: serverId, NextRefresh: se.NextRefresh, Report: events.NewReport(se)})
+}
+
+func (api *statsAPI) preflight(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+	w.Header().Set("Access-Control-Allow-Methods", "GET")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorized checks the token of the server, unknown servers and servers without a token are rejected alike.
+func (api *statsAPI) authorized(r *http.Request, serverId string) bool {
+	server, err := api.store.ReadServer(serverId)
+	if err != nil || server == nil || server.ApiToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(server.ApiToken)) == 1
+}
+
+// statsAPIURL is the address of the latest stats of the server, a path when the public url of the bot is unknown.
+func statsAPIURL(publicURL, serverId string) string {
+	return strings.TrimSuffix(publicURL, "/") + "/api/servers/" + serverId + "/latest"
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// announcedBucket holds the calendar events already announced per server, so changing the settings of a server
+// or restarting the bot inside the lead time of a raid does not announce it again.
+var announcedBucket = []byte("announced")
+
+// MarkAnnounced remembers the calendar event as announced and reports whether it was not announced before.
+// Events that started more than a day ago are forgotten on the way.
+func (s *Store) MarkAnnounced(serverId, uid string, start time.Time) (bool, error) {
+	announced := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(announcedBucket).Bucket([]byte(serverId)); b != nil {
+			announced = b.Get([]byte(uid)) != nil
+		}
+		return nil
+	})
+	if err != nil || announced {
+		return false, err
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(announcedBucket).CreateBucketIfNotExists([]byte(serverId))
+		if err != nil {
+			return err
+		}
+		if b.Get([]byte(uid)) != nil {
+			announced = true
+			return nil
+		}
+		var outdated [][]byte
+		err = b.ForEach(func(k, v []byte) error {
+			var t time.Time
+			if err := t.UnmarshalText(v); err != nil || time.Since(t) > 24*time.Hour {
+				outdated = append(outdated, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range outdated {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		v, _ := start.MarshalText()
+		return b.Put([]byte(uid), v)
+	})
+	return err == nil && !announced, err
+}
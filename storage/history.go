@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var historyBucket = []byte("history")
+
+// ReportSummary is an ended raid night kept for the season leaderboard.
+type ReportSummary struct {
+	Code      string          `json:"code"`
+	Title     string          `json:"title"`
+	Zone      string          `json:"zone"`
+	StartedAt time.Time       `json:"started_at"`
+	Kills     int             `json:"kills"`
+	Wipes     int             `json:"wipes"`
+	Players   []PlayerSummary `json:"players"`
+}
+
+type PlayerSummary struct {
+	Name   string  `json:"name"`
+	Class  string  `json:"class,omitempty"`
+	Deaths int     `json:"deaths"`
+	Parse  float64 `json:"parse,omitempty"`
+}
+
+// SaveReportSummary stores the summary of the report, a summary saved again replaces the previous one.
+func (s *Store) SaveReportSummary(serverId string, rs ReportSummary) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(historyBucket).CreateBucketIfNotExists([]byte(serverId))
+		if err != nil {
+			return err
+		}
+		data, _ := json.Marshal(&rs)
+		return b.Put([]byte(rs.Code), data)
+	})
+}
+
+func (s *Store) HasReportSummary(serverId, code string) (bool, error) {
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(historyBucket).Bucket([]byte(serverId)); b != nil {
+			found = b.Get([]byte(code)) != nil
+		}
+		return nil
+	})
+	return found, err
+}
+
+// ReportSummaries returns the stored summaries of the server, newest first.
+func (s *Store) ReportSummaries(serverId string) ([]ReportSummary, error) {
+	var summaries []ReportSummary
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket).Bucket([]byte(serverId))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, data []byte) error {
+			var rs ReportSummary
+			if err := json.Unmarshal(data, &rs); err != nil {
+				return err
+			}
+			summaries = append(summaries, rs)
+			return nil
+		})
+	})
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].StartedAt.After(summaries[j].StartedAt) })
+	return summaries, err
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+)
+
+// seriesBucket holds the points of the servers recording time series. They are kept in the bolt database the bot
+// already runs on instead of SQLite or a Prometheus remote-write target, so the bot stays a single binary with a
+// single data file and every server reads only its own series behind its stats api token.
+var seriesBucket = []byte("series")
+
+// seriesRetention is how long points are kept, older ones are pruned on write.
+const seriesRetention = 90 * 24 * time.Hour
+
+// Point is the state of a report at one update.
+type Point struct {
+	Time   time.Time   `json:"time"`
+	Report string      `json:"report"`
+	Deaths int         `json:"deaths"`
+	Kills  int         `json:"kills"`
+	Wipes  int         `json:"wipes"`
+	Bosses []BossPoint `json:"bosses,omitempty"`
+}
+
+type BossPoint struct {
+	Name        string  `json:"name"`
+	Difficulty  int     `json:"difficulty"`
+	Pulls       int     `json:"pulls"`
+	BestPercent float64 `json:"best_percent"`
+}
+
+// AddPoint appends the point to the series of the server, keys are big endian timestamps so points are kept in time order.
+// A point equal to the last one is skipped, the series only changes when the report does.
+func (s *Store) AddPoint(serverId string, p Point) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(seriesBucket).CreateBucketIfNotExists([]byte(serverId))
+		if err != nil {
+			return err
+		}
+		if _, v := b.Cursor().Last(); v != nil {
+			var last Point
+			if err := json.Unmarshal(v, &last); err == nil && last.sameAs(p) {
+				return nil
+			}
+		}
+		data, _ := json.Marshal(&p)
+		if err := b.Put(seriesKey(p.Time), data); err != nil {
+			return err
+		}
+		cutoff := seriesKey(time.Now().Add(-seriesRetention))
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteSeries drops the recorded points of the server.
+func (s *Store) DeleteSeries(serverId string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(seriesBucket).DeleteBucket([]byte(serverId)); err != nil && !errors.Is(err, bolterrors.ErrBucketNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// Points returns the points of the server between from and to.
+func (s *Store) Points(serverId string, from, to time.Time) ([]Point, error) {
+	var points []Point
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(seriesBucket).Bucket([]byte(serverId))
+		if b == nil {
+			return nil
+		}
+		end := seriesKey(to)
+		c := b.Cursor()
+		for k, v := c.Seek(seriesKey(from)); k != nil && bytes.Compare(k, end) <= 0; k, v = c.Next() {
+			var p Point
+			if err := json.Unmarshal(v, &p); err != nil {
+				return err
+			}
+			points = append(points, p)
+		}
+		return nil
+	})
+	return points, err
+}
+
+func (p Point) sameAs(o Point) bool {
+	return p.Report == o.Report && p.Deaths == o.Deaths && p.Kills == o.Kills && p.Wipes == o.Wipes && slices.Equal(p.Bosses, o.Bosses)
+}
+
+func seriesKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
@@ -2,24 +2,85 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
 )
 
-var serversBucket = []byte("servers")
+var (
+	serversBucket  = []byte("servers")
+	claimsBucket   = []byte("claims")
+	usageBucket    = []byte("usage")
+	flagsBucket    = []byte("flags")
+	scheduleBucket = []byte("schedule")
+	verifiedBucket = []byte("verified")
+	bindingsBucket = []byte("bindings")
+)
+
+const (
+	EmbedModeDetailed = "detailed"
+	EmbedModeCompact  = "compact"
+
+	ThemeClassic    = "classic"
+	ThemeDifficulty = "difficulty"
+
+	LayoutEmbed      = "embed"
+	LayoutComponents = "components"
+
+	SpoilersOff     = ""
+	SpoilersTags    = "spoiler"
+	SpoilersGeneric = "generic"
+)
 
 type Store struct {
 	db *bolt.DB
+
+	// pending holds the usage recorded since the last FlushUsage, commands and updates only count in memory
+	// so they never wait on a bolt transaction
+	mu      sync.Mutex
+	pending map[string]*Usage
 }
 
 func New(db *bolt.DB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, pending: make(map[string]*Usage)}
 }
 
 func MustInitDB(db *bolt.DB) {
 	err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte("servers"))
-		return err
+		for _, bucket := range [][]byte{serversBucket, claimsBucket, usageBucket, flagsBucket, scheduleBucket, seriesBucket, historyBucket, verifiedBucket, bindingsBucket, threadsBucket, announcedBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		// spreadsheets and mirrors set up before bindings existed belong to the server using them
+		bindings := tx.Bucket(bindingsBucket)
+		return tx.Bucket(serversBucket).ForEach(func(_, v []byte) error {
+			var srv Server
+			if err := json.Unmarshal(v, &srv); err != nil {
+				return nil
+			}
+			var resources []string
+			if srv.SpreadsheetId != "" {
+				resources = append(resources, SpreadsheetResource(srv.SpreadsheetId))
+			}
+			for platform, target := range srv.Mirrors {
+				resources = append(resources, MirrorResource(platform, target))
+			}
+			for _, r := range resources {
+				if bindings.Get([]byte(r)) != nil {
+					continue
+				}
+				if err := bindings.Put([]byte(r), []byte(srv.ServerId)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
 	})
 	if err != nil {
 		panic(err)
@@ -27,10 +88,37 @@ func MustInitDB(db *bolt.DB) {
 }
 
 type Server struct {
-	ServerId   string `json:"server_id"`
-	ChannelId  string `json:"channel_id"`
-	WlGuildId  int64  `json:"wl_guild_id"`
-	WipeCutoff int64  `json:"wipe_cutoff"`
+	ServerId         string            `json:"server_id"`
+	ChannelId        string            `json:"channel_id"`
+	WlGuildId        int64             `json:"wl_guild_id"`
+	Site             string            `json:"site,omitempty"`
+	WipeCutoff       int64             `json:"wipe_cutoff"`
+	PollInterval     int64             `json:"poll_interval,omitempty"`
+	EmbedMode        string            `json:"embed_mode,omitempty"`
+	Theme            string            `json:"theme,omitempty"`
+	Layout           string            `json:"layout,omitempty"`
+	Medals           []string          `json:"medals,omitempty"`
+	SpoilerMode      string            `json:"spoiler_mode,omitempty"`
+	Locale           string            `json:"locale,omitempty"`
+	MentionClaims    bool              `json:"mention_claims,omitempty"`
+	WebhookId        string            `json:"webhook_id,omitempty"`
+	WebhookToken     string            `json:"webhook_token,omitempty"`
+	WebhookName      string            `json:"webhook_name,omitempty"`
+	WebhookAvatar    string            `json:"webhook_avatar,omitempty"`
+	EventsURL        string            `json:"events_url,omitempty"`
+	EventsSecret     string            `json:"events_secret,omitempty"`
+	SpreadsheetId    string            `json:"spreadsheet_id,omitempty"`
+	SpreadsheetTab   string            `json:"spreadsheet_tab,omitempty"`
+	CalendarURL      string            `json:"calendar_url,omitempty"`
+	CalendarAnnounce bool              `json:"calendar_announce,omitempty"`
+	Mirrors          map[string]string `json:"mirrors,omitempty"`
+	Streamers        map[string]string `json:"streamers,omitempty"`
+	ScheduledEvents  bool              `json:"scheduled_events,omitempty"`
+	RaidThreads      bool              `json:"raid_threads,omitempty"`
+	ApiToken         string            `json:"api_token,omitempty"`
+	TimeSeries       bool              `json:"time_series,omitempty"`
+	LinkChannels     []string          `json:"link_channels,omitempty"`
+	VerifiedClaims   bool              `json:"verified_claims,omitempty"`
 }
 
 func (s *Store) SaveServer(server Server) error {
@@ -57,9 +145,407 @@ func (s *Store) ReadServer(serverId string) (*Server, error) {
 	return server, nil
 }
 
+func (s *Store) ListServers() ([]Server, error) {
+	var servers []Server
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(serversBucket)
+		return b.ForEach(func(_, data []byte) error {
+			var srv Server
+			if err := json.Unmarshal(data, &srv); err != nil {
+				return err
+			}
+			servers = append(servers, srv)
+			return nil
+		})
+	})
+	return servers, err
+}
+
 func (s *Store) DeleteServer(serverId string) error {
+	s.mu.Lock()
+	delete(s.pending, serverId)
+	s.mu.Unlock()
 	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(claimsBucket).Delete([]byte(serverId)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(verifiedBucket).Delete([]byte(serverId)); err != nil {
+			return err
+		}
+		if err := unbind(tx, serverId); err != nil {
+			return err
+		}
+		if err := tx.Bucket(usageBucket).Delete([]byte(serverId)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(flagsBucket).Delete([]byte(serverId)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(scheduleBucket).Delete([]byte(serverId)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(seriesBucket).DeleteBucket([]byte(serverId)); err != nil && !errors.Is(err, bolterrors.ErrBucketNotFound) {
+			return err
+		}
+		if err := tx.Bucket(historyBucket).DeleteBucket([]byte(serverId)); err != nil && !errors.Is(err, bolterrors.ErrBucketNotFound) {
+			return err
+		}
+		if err := tx.Bucket(threadsBucket).DeleteBucket([]byte(serverId)); err != nil && !errors.Is(err, bolterrors.ErrBucketNotFound) {
+			return err
+		}
+		if err := tx.Bucket(announcedBucket).DeleteBucket([]byte(serverId)); err != nil && !errors.Is(err, bolterrors.ErrBucketNotFound) {
+			return err
+		}
 		b := tx.Bucket(serversBucket)
 		return b.Delete([]byte(serverId))
 	})
 }
+
+// ReadClaims returns claimed characters of the server, keyed by lowercased character name with discord user id as value.
+func (s *Store) ReadClaims(serverId string) (map[string]string, error) {
+	claims := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(claimsBucket)
+		data := b.Get([]byte(serverId))
+		if len(data) == 0 {
+			return nil
+		}
+		return json.Unmarshal(data, &claims)
+	})
+	return claims, err
+}
+
+// SaveClaim assigns the character to the user unless it is already claimed by someone else,
+// in which case the current owner is returned.
+func (s *Store) SaveClaim(serverId, character, userId string) (string, error) {
+	owner := userId
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(claimsBucket)
+		claims := make(map[string]string)
+		if data := b.Get([]byte(serverId)); len(data) > 0 {
+			if err := json.Unmarshal(data, &claims); err != nil {
+				return err
+			}
+		}
+		key := strings.ToLower(character)
+		if current, ok := claims[key]; ok && current != userId {
+			owner = current
+			return nil
+		}
+		claims[key] = userId
+		data, _ := json.Marshal(claims)
+		return b.Put([]byte(serverId), data)
+	})
+	return owner, err
+}
+
+func (s *Store) DeleteClaim(serverId, character string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(claimsBucket)
+		claims := make(map[string]string)
+		if data := b.Get([]byte(serverId)); len(data) > 0 {
+			if err := json.Unmarshal(data, &claims); err != nil {
+				return err
+			}
+		}
+		delete(claims, strings.ToLower(character))
+		data, _ := json.Marshal(claims)
+		if err := b.Put([]byte(serverId), data); err != nil {
+			return err
+		}
+		return deleteVerified(tx, serverId, character)
+	})
+}
+
+// ReadVerifiedClaims returns the claims of the server proven with a Battle.net login, keyed like ReadClaims.
+func (s *Store) ReadVerifiedClaims(serverId string) (map[string]string, error) {
+	verified := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(verifiedBucket).Get([]byte(serverId))
+		if len(data) == 0 {
+			return nil
+		}
+		return json.Unmarshal(data, &verified)
+	})
+	return verified, err
+}
+
+// SaveVerifiedClaims assigns the characters to the user as verified claims, taking them over from whoever claimed them before.
+func (s *Store) SaveVerifiedClaims(serverId, userId string, characters []string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{claimsBucket, verifiedBucket} {
+			b := tx.Bucket(bucket)
+			claims := make(map[string]string)
+			if data := b.Get([]byte(serverId)); len(data) > 0 {
+				if err := json.Unmarshal(data, &claims); err != nil {
+					return err
+				}
+			}
+			for _, character := range characters {
+				claims[strings.ToLower(character)] = userId
+			}
+			data, _ := json.Marshal(claims)
+			if err := b.Put([]byte(serverId), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func deleteVerified(tx *bolt.Tx, serverId, character string) error {
+	b := tx.Bucket(verifiedBucket)
+	data := b.Get([]byte(serverId))
+	if len(data) == 0 {
+		return nil
+	}
+	verified := make(map[string]string)
+	if err := json.Unmarshal(data, &verified); err != nil {
+		return err
+	}
+	delete(verified, strings.ToLower(character))
+	data, _ = json.Marshal(verified)
+	return b.Put([]byte(serverId), data)
+}
+
+// SpreadsheetResource and MirrorResource name the resources of Bind.
+func SpreadsheetResource(spreadsheetId string) string {
+	return "sheet:" + spreadsheetId
+}
+
+func MirrorResource(platform, target string) string {
+	return "mirror:" + platform + ":" + target
+}
+
+// Bind reserves the resource for the server unless another server bound it first, in which case that server
+// is returned. Every server writes to spreadsheets and chats through the same operator accounts, so the binding
+// keeps servers out of the ones set up for someone else. Bindings are released when the server is deleted.
+func (s *Store) Bind(resource, serverId string) (string, error) {
+	owner := serverId
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bindingsBucket)
+		if current := b.Get([]byte(resource)); current != nil && string(current) != serverId {
+			owner = string(current)
+			return nil
+		}
+		return b.Put([]byte(resource), []byte(serverId))
+	})
+	return owner, err
+}
+
+func unbind(tx *bolt.Tx, serverId string) error {
+	b := tx.Bucket(bindingsBucket)
+	var bound [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		if string(v) == serverId {
+			bound = append(bound, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range bound {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScheduledEvent is a discord scheduled event created for a calendar event.
+type ScheduledEvent struct {
+	EventId string    `json:"event_id"`
+	Name    string    `json:"name"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+}
+
+// ReadSchedule returns the scheduled events of the server keyed by calendar event uid.
+func (s *Store) ReadSchedule(serverId string) (map[string]ScheduledEvent, error) {
+	schedule := make(map[string]ScheduledEvent)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(scheduleBucket).Get([]byte(serverId))
+		if len(data) == 0 {
+			return nil
+		}
+		return json.Unmarshal(data, &schedule)
+	})
+	return schedule, err
+}
+
+func (s *Store) SaveSchedule(serverId string, schedule map[string]ScheduledEvent) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, _ := json.Marshal(schedule)
+		return tx.Bucket(scheduleBucket).Put([]byte(serverId), data)
+	})
+}
+
+type Usage struct {
+	ServerId    string         `json:"server_id"`
+	Commands    map[string]int `json:"commands,omitempty"`
+	Updates     int            `json:"updates"`
+	LastCommand time.Time      `json:"last_command,omitzero"`
+	LastUpdate  time.Time      `json:"last_update,omitzero"`
+}
+
+func (u Usage) TotalCommands() int {
+	total := 0
+	for _, n := range u.Commands {
+		total += n
+	}
+	return total
+}
+
+// LastActivity returns the time of the latest command or update.
+func (u Usage) LastActivity() time.Time {
+	if u.LastCommand.After(u.LastUpdate) {
+		return u.LastCommand
+	}
+	return u.LastUpdate
+}
+
+// add merges the usage recorded later into u.
+func (u *Usage) add(later Usage) {
+	for command, n := range later.Commands {
+		if u.Commands == nil {
+			u.Commands = make(map[string]int)
+		}
+		u.Commands[command] += n
+	}
+	u.Updates += later.Updates
+	if later.LastCommand.After(u.LastCommand) {
+		u.LastCommand = later.LastCommand
+	}
+	if later.LastUpdate.After(u.LastUpdate) {
+		u.LastUpdate = later.LastUpdate
+	}
+}
+
+// RecordCommand counts the command in memory, FlushUsage stores it.
+func (s *Store) RecordCommand(serverId, command string) {
+	s.recordUsage(serverId, Usage{Commands: map[string]int{command: 1}, LastCommand: time.Now()})
+}
+
+// RecordUpdate counts the update in memory, FlushUsage stores it.
+func (s *Store) RecordUpdate(serverId string) {
+	s.recordUsage(serverId, Usage{Updates: 1, LastUpdate: time.Now()})
+}
+
+func (s *Store) recordUsage(serverId string, usage Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.pending[serverId]
+	if !ok {
+		u = &Usage{ServerId: serverId}
+		s.pending[serverId] = u
+	}
+	u.add(usage)
+}
+
+// FlushUsage adds the usage recorded since the last flush to the stored usage in a single transaction,
+// it is kept for the next flush when the transaction fails.
+func (s *Store) FlushUsage() error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]*Usage)
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usageBucket)
+		for serverId, recorded := range pending {
+			usage := Usage{ServerId: serverId}
+			if data := b.Get([]byte(serverId)); len(data) > 0 {
+				if err := json.Unmarshal(data, &usage); err != nil {
+					return err
+				}
+			}
+			usage.add(*recorded)
+			data, _ := json.Marshal(&usage)
+			if err := b.Put([]byte(serverId), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for serverId, recorded := range pending {
+			s.recordUsage(serverId, *recorded)
+		}
+	}
+	return err
+}
+
+// ListUsage returns the stored usage of every server together with the usage not flushed yet.
+func (s *Store) ListUsage() ([]Usage, error) {
+	stored := make(map[string]*Usage)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usageBucket)
+		return b.ForEach(func(_, data []byte) error {
+			var u Usage
+			if err := json.Unmarshal(data, &u); err != nil {
+				return err
+			}
+			stored[u.ServerId] = &u
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	for serverId, recorded := range s.pending {
+		u, ok := stored[serverId]
+		if !ok {
+			u = &Usage{ServerId: serverId}
+			stored[serverId] = u
+		}
+		u.add(*recorded)
+	}
+	s.mu.Unlock()
+	usage := make([]Usage, 0, len(stored))
+	for _, u := range stored {
+		usage = append(usage, *u)
+	}
+	slices.SortFunc(usage, func(a, b Usage) int { return strings.Compare(a.ServerId, b.ServerId) })
+	return usage, nil
+}
+
+// GlobalFlags is the scope of feature flag overrides applied to every server.
+const GlobalFlags = "global"
+
+// ReadFlags returns the feature flag overrides of the scope, a server id or GlobalFlags.
+func (s *Store) ReadFlags(scope string) (map[string]bool, error) {
+	flags := make(map[string]bool)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(flagsBucket).Get([]byte(scope))
+		if len(data) == 0 {
+			return nil
+		}
+		return json.Unmarshal(data, &flags)
+	})
+	return flags, err
+}
+
+// SetFlag overrides the flag in the scope, a nil value removes the override.
+func (s *Store) SetFlag(scope, name string, value *bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(flagsBucket)
+		flags := make(map[string]bool)
+		if data := b.Get([]byte(scope)); len(data) > 0 {
+			if err := json.Unmarshal(data, &flags); err != nil {
+				return err
+			}
+		}
+		if value == nil {
+			delete(flags, name)
+		} else {
+			flags[name] = *value
+		}
+		data, _ := json.Marshal(flags)
+		return b.Put([]byte(scope), data)
+	})
+}
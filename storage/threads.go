@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var threadsBucket = []byte("threads")
+
+// SaveRaidThread remembers the thread the report is posted into, so a restart keeps posting into the same thread.
+func (s *Store) SaveRaidThread(serverId, channelId, reportCode, threadId string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(threadsBucket).CreateBucketIfNotExists([]byte(serverId))
+		if err != nil {
+			return err
+		}
+		return b.Put(threadKey(channelId, reportCode), []byte(threadId))
+	})
+}
+
+// RaidThread returns the thread of the report, empty when the report has none.
+func (s *Store) RaidThread(serverId, channelId, reportCode string) (string, error) {
+	threadId := ""
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(threadsBucket).Bucket([]byte(serverId)); b != nil {
+			threadId = string(b.Get(threadKey(channelId, reportCode)))
+		}
+		return nil
+	})
+	return threadId, err
+}
+
+// RaidThreads returns the threads of the reports of the server posted to the channel.
+func (s *Store) RaidThreads(serverId, channelId string) ([]string, error) {
+	var threadIds []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(threadsBucket).Bucket([]byte(serverId))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if strings.HasPrefix(string(k), channelId+":") {
+				threadIds = append(threadIds, string(v))
+			}
+			return nil
+		})
+	})
+	return threadIds, err
+}
+
+// DeleteRaidThread forgets the thread of the report once it is archived.
+func (s *Store) DeleteRaidThread(serverId, channelId, reportCode string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(threadsBucket).Bucket([]byte(serverId)); b != nil {
+			return b.Delete(threadKey(channelId, reportCode))
+		}
+		return nil
+	})
+}
+
+func threadKey(channelId, reportCode string) []byte {
+	return []byte(channelId + ":" + reportCode)
+}
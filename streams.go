@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"bot/twitch"
+	"bot/watcher"
+)
+
+var twitchLoginPattern = regexp.MustCompile(`^[a-z0-9_]{3,25}$`)
+
+// twitchLogin accepts a channel name or a link to the channel.
+func twitchLogin(channel string) (string, bool) {
+	login := strings.ToLower(strings.TrimSpace(channel))
+	login = strings.TrimPrefix(login, "https://")
+	login = strings.TrimPrefix(login, "http://")
+	login = strings.TrimPrefix(login, "www.")
+	login = strings.TrimPrefix(login, "twitch.tv/")
+	login = strings.TrimSuffix(login, "/")
+	return login, twitchLoginPattern.MatchString(login)
+}
+
+// liveStream is a tracked raider streaming on twitch.
+type liveStream struct {
+	Character string
+	Stream    twitch.Stream
+}
+
+// liveStreams returns the tracked streamers of the server who are live and present in the report, only live
+// reports look them up.
+func liveStreams(client *twitch.Client, stats watcher.StatsEvent) []liveStream {
+	if client == nil || !stats.Live {
+		return nil
+	}
+	tracked := reportStreamers(stats)
+	if len(tracked) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	streams, err := client.LiveStreams(ctx, slices.Collect(maps.Values(tracked)))
+	if err != nil {
+		slog.Error("error loading twitch streams", slog.String("server", stats.Server.ServerId), "error", err)
+	}
+	return matchStreams(tracked, streams)
+}
+
+// cachedStreams is liveStreams from the streams already looked up, for interactions that can't wait on twitch.
+func cachedStreams(client *twitch.Client, stats watcher.StatsEvent) []liveStream {
+	if client == nil || !stats.Live {
+		return nil
+	}
+	tracked := reportStreamers(stats)
+	return matchStreams(tracked, client.CachedStreams(slices.Collect(maps.Values(tracked))))
+}
+
+// reportStreamers returns the logins of the tracked streamers who play in the report, keyed by character.
+func reportStreamers(stats watcher.StatsEvent) map[string]string {
+	tracked := make(map[string]string)
+	for _, player := range stats.Players {
+		if login, ok := stats.Server.Streamers[strings.ToLower(player)]; ok {
+			tracked[player] = login
+		}
+	}
+	return tracked
+}
+
+func matchStreams(tracked map[string]string, streams []twitch.Stream) []liveStream {
+	var live []liveStream
+	for character, login := range tracked {
+		for _, s := range streams {
+			if strings.EqualFold(s.Login, login) {
+				live = append(live, liveStream{Character: character, Stream: s})
+			}
+		}
+	}
+	slices.SortFunc(live, func(a, b liveStream) int { return strings.Compare(a.Character, b.Character) })
+	return live
+}
+
+func formatStreams(streams []liveStream) string {
+	var sb strings.Builder
+	for _, s := range streams {
+		fmt.Fprintf(&sb, "🔴 [%v](%v) · %v\n", s.Stream.Name, s.Stream.URL(), s.Character)
+	}
+	return sb.String()
+}
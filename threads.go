@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"bot/watcher"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// raidThreadArchiveAfter is the auto archive duration of raid threads in minutes, threads are archived
+// right after the report ends and this only applies when the end is missed.
+const raidThreadArchiveAfter = 1440
+
+// openRaidThread starts the thread of a raid night in the channel of the server. Forum channels get a post
+// starting with the report link, text channels a thread without a starter message and announcement channels
+// an announcement thread.
+func openRaidThread(s *discordgo.Session, se watcher.StatsEvent) (string, error) {
+	channel, err := serverChannel(s, se.Server.ChannelId)
+	if err != nil {
+		return "", err
+	}
+	start := &discordgo.ThreadStart{
+		Name:                truncate(fmt.Sprintf("%v · %v", se.Title, se.StartedAt.Format("2006-01-02")), 100),
+		AutoArchiveDuration: raidThreadArchiveAfter,
+	}
+	var thread *discordgo.Channel
+	switch channel.Type {
+	case discordgo.ChannelTypeGuildForum:
+		thread, err = s.ForumThreadStartComplex(channel.ID, start, &discordgo.MessageSend{Content: se.URL})
+	case discordgo.ChannelTypeGuildNews:
+		start.Type = discordgo.ChannelTypeGuildNewsThread
+		thread, err = s.ThreadStartComplex(channel.ID, start)
+	default:
+		start.Type = discordgo.ChannelTypeGuildPublicThread
+		thread, err = s.ThreadStartComplex(channel.ID, start)
+	}
+	if err != nil {
+		return "", err
+	}
+	return thread.ID, nil
+}
+
+// serverChannel returns the channel from the state, or from the api when it is not cached.
+func serverChannel(s *discordgo.Session, channelId string) (*discordgo.Channel, error) {
+	if channel, err := s.State.Channel(channelId); err == nil {
+		return channel, nil
+	}
+	return s.Channel(channelId)
+}
+
+// isForum reports whether the channel only takes posts, messages can only be sent to threads in it.
+func isForum(s *discordgo.Session, channelId string) bool {
+	channel, err := serverChannel(s, channelId)
+	return err == nil && channel.Type == discordgo.ChannelTypeGuildForum
+}
+
+func archiveRaidThread(s *discordgo.Session, threadId string) error {
+	archived := true
+	_, err := s.ChannelEdit(threadId, &discordgo.ChannelEdit{Archived: &archived})
+	return err
+}
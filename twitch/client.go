@@ -0,0 +1,189 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+const (
+	tokenURL   = "https://id.twitch.tv/oauth2/token"
+	streamsURL = "https://api.twitch.tv/helix/streams"
+
+	// maxLogins is the number of user_login parameters helix accepts in one request.
+	maxLogins = 100
+	// failedTTL keeps logins that failed to load from being looked up again on every update while twitch is down.
+	failedTTL = 30 * time.Second
+)
+
+type Stream struct {
+	Login   string
+	Name    string
+	Title   string
+	Game    string
+	Viewers int
+}
+
+func (s Stream) URL() string {
+	return "https://www.twitch.tv/" + s.Login
+}
+
+type streamsResp struct {
+	Data []struct {
+		UserLogin   string `json:"user_login"`
+		UserName    string `json:"user_name"`
+		Title       string `json:"title"`
+		GameName    string `json:"game_name"`
+		ViewerCount int    `json:"viewer_count"`
+		Type        string `json:"type"`
+	} `json:"data"`
+}
+
+type tokenResp struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type Client struct {
+	clientID     string
+	clientSecret string
+
+	resty *resty.Client
+	// cache holds the stream of every looked up login, offline channels are cached as nil
+	cache *ttlcache.Cache[string, *Stream]
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewClient(clientId, clientSecret string) *Client {
+	cache := ttlcache.New[string, *Stream](
+		ttlcache.WithTTL[string, *Stream](2 * time.Minute),
+	)
+	go cache.Start()
+	return &Client{
+		clientID:     clientId,
+		clientSecret: clientSecret,
+		resty:        resty.New().SetTimeout(10 * time.Second),
+		cache:        cache,
+	}
+}
+
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Add(time.Minute).Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	var tr tokenResp
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetFormData(map[string]string{
+			"client_id":     c.clientID,
+			"client_secret": c.clientSecret,
+			"grant_type":    "client_credentials",
+		}).
+		SetResult(&tr).
+		Post(tokenURL)
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("twitch oauth token failed: %s: %s", resp.Status(), string(resp.Body()))
+	}
+	c.token = tr.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// LiveStreams returns the streams of the channels that are live, lookups are cached for two minutes.
+func (c *Client) LiveStreams(ctx context.Context, logins []string) ([]Stream, error) {
+	var (
+		live    []Stream
+		missing []string
+	)
+	for _, login := range logins {
+		login = strings.ToLower(login)
+		if item := c.cache.Get(login); item != nil {
+			if item.Value() != nil {
+				live = append(live, *item.Value())
+			}
+			continue
+		}
+		missing = append(missing, login)
+	}
+
+	for len(missing) > 0 {
+		batch := missing[:min(len(missing), maxLogins)]
+		missing = missing[len(batch):]
+		streams, err := c.streams(ctx, batch)
+		if err != nil {
+			for _, login := range batch {
+				c.cache.Set(login, nil, failedTTL)
+			}
+			return live, err
+		}
+		for _, login := range batch {
+			c.cache.Set(login, nil, ttlcache.DefaultTTL)
+		}
+		for _, s := range streams {
+			c.cache.Set(s.Login, &s, ttlcache.DefaultTTL)
+			live = append(live, s)
+		}
+	}
+	return live, nil
+}
+
+// CachedStreams returns the streams of the channels known to be live without making any requests.
+func (c *Client) CachedStreams(logins []string) []Stream {
+	var live []Stream
+	for _, login := range logins {
+		if item := c.cache.Get(strings.ToLower(login)); item != nil && item.Value() != nil {
+			live = append(live, *item.Value())
+		}
+	}
+	return live
+}
+
+func (c *Client) streams(ctx context.Context, logins []string) ([]Stream, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out streamsResp
+	resp, err := c.resty.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		SetHeader("Client-Id", c.clientID).
+		SetQueryParamsFromValues(map[string][]string{"user_login": logins}).
+		SetResult(&out).
+		Get(streamsURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("twitch streams: %s: %s", resp.Status(), string(resp.Body()))
+	}
+
+	streams := make([]Stream, 0, len(out.Data))
+	for _, d := range out.Data {
+		if d.Type != "live" {
+			continue
+		}
+		streams = append(streams, Stream{
+			Login:   strings.ToLower(d.UserLogin),
+			Name:    d.UserName,
+			Title:   d.Title,
+			Game:    d.GameName,
+			Viewers: d.ViewerCount,
+		})
+	}
+	return streams, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"bot/events"
+	"bot/i18n"
+	"bot/storage"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// verifyTimeout is how long a Battle.net login link stays valid.
+const verifyTimeout = 10 * time.Minute
+
+// verifyRequest is a pending Battle.net login, keyed by the random OAuth state.
+type verifyRequest struct {
+	ServerId   string
+	ServerName string
+	UserId     string
+	UserName   string
+	Locale     discordgo.Locale
+}
+
+// verifyConfirmation is a finished login waiting for the user to confirm the Discord account, keyed by a random token.
+type verifyConfirmation struct {
+	verifyRequest
+	Characters []string
+}
+
+// confirmPage asks whoever finished the login to confirm the Discord account the characters go to. The login link
+// can be passed on, so the person logging in has to see whose account it was started for.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!doctype html>
+<html lang="{{.Lang}}">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width"><title>Battle.net</title></head>
+<body>
+<p>{{.Question}}</p>
+<form method="post" action="confirm">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">{{.Confirm}}</button>
+</form>
+</body>
+</html>
+`))
+
+// battleNetLogin lets users prove the ownership of their characters by logging in with Battle.net,
+// characters of the account found in the guild roster become verified claims of the user once confirmed.
+type battleNetLogin struct {
+	store       *storage.Store
+	guilds      *guildLookup
+	pending     *ttlcache.Cache[string, verifyRequest]
+	confirming  *ttlcache.Cache[string, verifyConfirmation]
+	redirectURI string
+}
+
+func newBattleNetLogin(store *storage.Store, guilds *guildLookup, publicURL string) *battleNetLogin {
+	pending := ttlcache.New[string, verifyRequest](
+		ttlcache.WithTTL[string, verifyRequest](verifyTimeout),
+	)
+	go pending.Start()
+	confirming := ttlcache.New[string, verifyConfirmation](
+		ttlcache.WithTTL[string, verifyConfirmation](verifyTimeout),
+	)
+	go confirming.Start()
+	l := &battleNetLogin{store: store, guilds: guilds, pending: pending, confirming: confirming}
+	if publicURL != "" {
+		l.redirectURI = strings.TrimSuffix(publicURL, "/") + "/battlenet/callback"
+	}
+	return l
+}
+
+// enabled reports whether logins can complete, they need Battle.net credentials and a public callback.
+func (l *battleNetLogin) enabled() bool {
+	return l.guilds.rosterEnabled() && l.redirectURI != ""
+}
+
+// start returns the login link for the request, it can be used once.
+func (l *battleNetLogin) start(req verifyRequest) string {
+	state := events.NewSecret()
+	l.pending.Set(state, req, ttlcache.DefaultTTL)
+	return l.guilds.bnetClient.AuthorizeURL(l.redirectURI, state)
+}
+
+func (l *battleNetLogin) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /battlenet/callback", l.callback)
+	mux.HandleFunc("POST /battlenet/confirm", l.confirm)
+}
+
+func (l *battleNetLogin) callback(w http.ResponseWriter, r *http.Request) {
+	item, ok := l.pending.GetAndDelete(r.URL.Query().Get("state"))
+	if !ok {
+		http.Error(w, i18n.T(i18n.Fallback, "verify.page_expired"), http.StatusBadRequest)
+		return
+	}
+	req := item.Value()
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, i18n.T(req.Locale, "verify.page_denied"), http.StatusBadRequest)
+		return
+	}
+	server, err := l.store.ReadServer(req.ServerId)
+	if err != nil || server == nil {
+		slog.Error("error reading configuration", slog.String("server", req.ServerId), "error", err)
+		http.Error(w, i18n.T(req.Locale, "verify.page_failed"), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	guild, err := l.guilds.guild(ctx, server.WlGuildId)
+	if err != nil {
+		slog.Error("error loading guild", slog.String("server", req.ServerId), "error", err)
+		http.Error(w, i18n.T(req.Locale, "verify.page_failed"), http.StatusBadGateway)
+		return
+	}
+	roster, err := l.guilds.roster(ctx, server.WlGuildId)
+	if err != nil {
+		slog.Error("error loading guild roster", slog.String("server", req.ServerId), "error", err)
+		http.Error(w, i18n.T(req.Locale, "verify.page_failed"), http.StatusBadGateway)
+		return
+	}
+	characters, err := l.guilds.bnetClient.AccountCharacters(ctx, guild.Region, code, l.redirectURI)
+	if err != nil {
+		slog.Error("error loading battle.net account", slog.String("server", req.ServerId), slog.String("user", req.UserId), "error", err)
+		http.Error(w, i18n.T(req.Locale, "verify.page_failed"), http.StatusBadGateway)
+		return
+	}
+
+	// claims are keyed by name alone, like the players of reports, so a name on several realms of the guild
+	// can't be told apart and is left out
+	var names []string
+	for _, ch := range characters {
+		if roster.Has(ch) && len(roster.Named(ch.Name)) == 1 {
+			names = append(names, ch.Name)
+		}
+	}
+	if len(names) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprintln(w, i18n.T(req.Locale, "verify.page_none", guild.Name))
+		return
+	}
+
+	token := events.NewSecret()
+	l.confirming.Set(token, verifyConfirmation{verifyRequest: req, Characters: names}, ttlcache.DefaultTTL)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = confirmPage.Execute(w, map[string]string{
+		"Lang":     string(req.Locale),
+		"Question": i18n.T(req.Locale, "verify.page_confirm", strings.Join(names, ", "), req.UserName, req.ServerName),
+		"Confirm":  i18n.T(req.Locale, "verify.page_confirm_button"),
+		"Token":    token,
+	})
+	if err != nil {
+		slog.Warn("error writing confirmation page", slog.String("server", req.ServerId), "error", err)
+	}
+}
+
+func (l *battleNetLogin) confirm(w http.ResponseWriter, r *http.Request) {
+	item, ok := l.confirming.GetAndDelete(r.PostFormValue("token"))
+	if !ok {
+		http.Error(w, i18n.T(i18n.Fallback, "verify.page_expired"), http.StatusBadRequest)
+		return
+	}
+	req := item.Value()
+	names := req.Characters
+	if err := l.store.SaveVerifiedClaims(req.ServerId, req.UserId, names); err != nil {
+		slog.Error("error saving verified claims", slog.String("server", req.ServerId), "error", err)
+		http.Error(w, i18n.T(req.Locale, "verify.page_failed"), http.StatusInternalServerError)
+		return
+	}
+	slog.Info("characters verified", slog.String("server", req.ServerId), slog.String("user", req.UserId), slog.Int("characters", len(names)))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintln(w, i18n.T(req.Locale, "verify.page_done", strings.Join(names, ", ")))
+}
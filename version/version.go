@@ -0,0 +1,48 @@
+package version
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Version and Commit are set at build time:
+//
+//	go build -ldflags "-X bot/version.Version=v1.2.0 -X bot/version.Commit=$(git rev-parse --short HEAD)"
+//
+// Without them the commit is taken from the vcs information embedded by the go toolchain.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+var buildInfo = sync.OnceValues(func() (commit string, dirty bool) {
+	if Commit != "" {
+		return Commit, false
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown", false
+	}
+	commit = "unknown"
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	return commit, dirty
+})
+
+// String returns the version with the commit, e.g. "v1.2.0 (3f2c1ab)".
+func String() string {
+	commit, dirty := buildInfo()
+	if dirty {
+		commit += "-dirty"
+	}
+	return Version + " (" + commit + ")"
+}
@@ -9,12 +9,9 @@ import (
 	"sync"
 	"time"
 
-	"github.com/go-resty/resty/v2"
-)
+	"bot/metrics"
 
-const (
-	tokenURL   = "https://www.warcraftlogs.com/oauth/token"
-	graphQLURL = "https://www.warcraftlogs.com/api/v2/client"
+	"github.com/go-resty/resty/v2"
 )
 
 type tokenResp struct {
@@ -37,7 +34,8 @@ type ReportDataContainer struct {
 }
 
 type ReportsList struct {
-	Data []Report `json:"data"`
+	Data         []Report `json:"data"`
+	HasMorePages bool     `json:"has_more_pages"`
 }
 
 type Report struct {
@@ -54,6 +52,7 @@ type Owner struct {
 }
 
 type Zone struct {
+	ID           int          `json:"id"`
 	Name         string       `json:"name"`
 	Difficulties []Difficulty `json:"difficulties"`
 }
@@ -64,6 +63,7 @@ type Difficulty struct {
 }
 
 type Client struct {
+	site         Site
 	clientID     string
 	clientSecret string
 
@@ -72,12 +72,19 @@ type Client struct {
 	mu        sync.RWMutex
 	token     string
 	expiresAt time.Time
+
+	outageMu  sync.Mutex
+	failures  int
+	threshold int
+	onOutage  func(err error)
+	onRecover func()
 }
 
-func NewClient(wlClientId, wlClientSecret string) (*Client, error) {
+func NewClient(site Site, wlClientId, wlClientSecret string) (*Client, error) {
 	r := resty.New()
 
 	c := &Client{
+		site:         site,
 		clientID:     wlClientId,
 		clientSecret: wlClientSecret,
 		resty:        r,
@@ -90,6 +97,10 @@ func NewClient(wlClientId, wlClientSecret string) (*Client, error) {
 
 const tokenSkew = 60 * time.Second
 
+func (c *Client) Site() Site {
+	return c.site
+}
+
 func (c *Client) ensureToken(ctx context.Context) error {
 	c.mu.RLock()
 	tok := c.token
@@ -102,6 +113,12 @@ func (c *Client) ensureToken(ctx context.Context) error {
 	return c.refreshToken(ctx)
 }
 
+func (c *Client) TokenValid() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token != "" && time.Now().Before(c.expiresAt)
+}
+
 func (c *Client) refreshToken(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -110,7 +127,7 @@ func (c *Client) refreshToken(ctx context.Context) error {
 		return nil
 	}
 
-	tok, exp, err := getToken(ctx, c.resty, c.clientID, c.clientSecret)
+	tok, exp, err := getToken(ctx, c.resty, c.site.tokenURL(), c.clientID, c.clientSecret)
 	if err != nil {
 		return err
 	}
@@ -119,6 +136,39 @@ func (c *Client) refreshToken(ctx context.Context) error {
 	return nil
 }
 
+// OnOutage registers handlers called once when threshold requests in a row have failed,
+// and once when a request succeeds again after that.
+func (c *Client) OnOutage(threshold int, onOutage func(err error), onRecover func()) {
+	c.outageMu.Lock()
+	defer c.outageMu.Unlock()
+	c.threshold = threshold
+	c.onOutage = onOutage
+	c.onRecover = onRecover
+}
+
+func (c *Client) trackResult(ctx context.Context, err error) {
+	if err != nil && ctx.Err() != nil {
+		// cancelled requests say nothing about the api
+		return
+	}
+	c.outageMu.Lock()
+	defer c.outageMu.Unlock()
+	if c.threshold <= 0 {
+		return
+	}
+	if err == nil {
+		if c.failures >= c.threshold && c.onRecover != nil {
+			go c.onRecover()
+		}
+		c.failures = 0
+		return
+	}
+	c.failures++
+	if c.failures == c.threshold && c.onOutage != nil {
+		go c.onOutage(err)
+	}
+}
+
 func (c *Client) FindReports(ctx context.Context, guildId int64, startTime time.Time) ([]Report, error) {
 	query := `
 query($guildID: Int!, $limit:Int!, $startTime: Float!){
@@ -155,7 +205,84 @@ query($guildID: Int!, $limit:Int!, $startTime: Float!){
 	return out.ReportData.Reports.Data, nil
 }
 
-func getToken(ctx context.Context, r *resty.Client, clientID, clientSecret string) (string, time.Time, error) {
+// Report returns the report with the code.
+func (c *Client) Report(ctx context.Context, code string) (Report, error) {
+	query := `
+query($code: String!){
+  reportData {
+    report(code: $code) {
+      code
+      title
+      startTime
+      endTime
+      owner {
+        name
+      }
+      zone {
+        name
+        difficulties {
+          name
+          sizes
+        }
+      }
+    }
+  }
+}`
+	var out struct {
+		ReportData struct {
+			Report *Report `json:"report"`
+		} `json:"reportData"`
+	}
+	if err := c.gql(ctx, query, map[string]interface{}{"code": code}, &out); err != nil {
+		return Report{}, err
+	}
+	if out.ReportData.Report == nil {
+		return Report{}, fmt.Errorf("report %v not found", code)
+	}
+	return *out.ReportData.Report, nil
+}
+
+// GuildReports returns a page of up to 100 reports of the guild started after startTime, newest first,
+// and whether older pages follow.
+func (c *Client) GuildReports(ctx context.Context, guildId int64, startTime time.Time, page int) ([]Report, bool, error) {
+	query := `
+query($guildID: Int!, $startTime: Float!, $page: Int!){
+  reportData {
+    reports(guildID: $guildID, limit: 100, startTime: $startTime, page: $page) {
+      data {
+        code
+        title
+        startTime
+        endTime
+        owner {
+          name
+        }
+        zone {
+          id
+          name
+          difficulties {
+            name
+            sizes
+          }
+        }
+      }
+      has_more_pages
+    }
+  }
+}`
+	vars := map[string]interface{}{
+		"guildID":   guildId,
+		"startTime": float64(startTime.UnixMilli()),
+		"page":      page,
+	}
+	var out ReportsData
+	if err := c.gql(ctx, query, vars, &out); err != nil {
+		return nil, false, err
+	}
+	return out.ReportData.Reports.Data, out.ReportData.Reports.HasMorePages, nil
+}
+
+func getToken(ctx context.Context, r *resty.Client, tokenURL, clientID, clientSecret string) (string, time.Time, error) {
 	var tr tokenResp
 	resp, err := r.R().
 		SetContext(ctx).
@@ -183,7 +310,14 @@ type gqlEnvelope struct {
 	Errors []gqlError      `json:"errors"`
 }
 
-func (c *Client) gql(ctx context.Context, query string, vars map[string]interface{}, out any) error {
+func (c *Client) gql(ctx context.Context, query string, vars map[string]interface{}, out any) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.WCLRequests.WithLabelValues(metrics.Result(err)).Inc()
+		metrics.WCLRequestDuration.Observe(time.Since(start).Seconds())
+		c.trackResult(ctx, err)
+	}()
+
 	if err := c.ensureToken(ctx); err != nil {
 		return err
 	}
@@ -202,7 +336,7 @@ func (c *Client) gql(ctx context.Context, query string, vars map[string]interfac
 			SetHeader("Content-Type", "application/json").
 			SetBody(reqBody).
 			SetResult(&env).
-			Post(graphQLURL)
+			Post(c.site.graphQLURL())
 		if err != nil {
 			return nil, err
 		}
@@ -242,19 +376,25 @@ func (c *Client) gql(ctx context.Context, query string, vars map[string]interfac
 type fightsResp struct {
 	ReportData struct {
 		Report struct {
-			Fights []Fight `json:"fights"`
+			Fights     []Fight `json:"fights"`
+			MasterData struct {
+				Actors []struct {
+					Name string `json:"name"`
+				} `json:"actors"`
+			} `json:"masterData"`
 		} `json:"report"`
 	} `json:"reportData"`
 }
 
 type Fight struct {
-	ID          int    `json:"id"`
-	EncounterID int    `json:"encounterID"`
-	Name        string `json:"name"`
-	StartTime   int64  `json:"startTime"`
-	EndTime     int64  `json:"endTime"`
-	Difficulty  int    `json:"difficulty"`
-	Kill        bool   `json:"kill"`
+	ID              int     `json:"id"`
+	EncounterID     int     `json:"encounterID"`
+	Name            string  `json:"name"`
+	StartTime       int64   `json:"startTime"`
+	EndTime         int64   `json:"endTime"`
+	Difficulty      int     `json:"difficulty"`
+	Kill            bool    `json:"kill"`
+	FightPercentage float64 `json:"fightPercentage"`
 }
 
 type eventsPage struct {
@@ -278,6 +418,12 @@ type DeathEvent struct {
 }
 
 func (c *Client) GetBossFights(ctx context.Context, reportCode string) ([]Fight, error) {
+	fights, _, err := c.bossFights(ctx, reportCode)
+	return fights, err
+}
+
+// bossFights returns the boss fights and the names of the players present in the report.
+func (c *Client) bossFights(ctx context.Context, reportCode string) ([]Fight, []string, error) {
 	const q = `
 query($code: String!) {
   reportData {
@@ -290,15 +436,25 @@ query($code: String!) {
         endTime
         difficulty
         kill
+        fightPercentage
+      }
+      masterData {
+        actors(type: "Player") {
+          name
+        }
       }
     }
   }
 }`
 	var out fightsResp
 	if err := c.gql(ctx, q, map[string]interface{}{"code": reportCode}, &out); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return out.ReportData.Report.Fights, nil
+	players := make([]string, 0, len(out.ReportData.Report.MasterData.Actors))
+	for _, a := range out.ReportData.Report.MasterData.Actors {
+		players = append(players, a.Name)
+	}
+	return out.ReportData.Report.Fights, players, nil
 }
 
 type PlayerTop struct {
@@ -306,18 +462,101 @@ type PlayerTop struct {
 	Value int
 }
 
+const (
+	DifficultyLFR    = 1
+	DifficultyNormal = 3
+	DifficultyHeroic = 4
+	DifficultyMythic = 5
+)
+
+type BossTally struct {
+	EncounterID int
+	Name        string
+	Difficulty  int
+	Kills       int
+	Wipes       int
+	BestPercent float64
+}
+
 type ReportDetails struct {
 	TopDeaths      []PlayerTop
 	TopFirstDeaths []PlayerTop
+	Kills          int
+	Wipes          int
+	Bosses         []BossTally
+	Difficulty     int
+	ProgKill       bool
+	TotalDeaths    int
+	LastPull       Pull
+	Players        []string
+}
+
+// Pull is the latest boss pull of a report.
+type Pull struct {
+	Name       string
+	Difficulty int
+	Kill       bool
+	Percent    float64
+	Deaths     int
+}
+
+// isProgKill reports whether the last pull is a kill of a boss the raid has wiped on earlier in the same report.
+func isProgKill(fights []Fight) bool {
+	if len(fights) == 0 {
+		return false
+	}
+	last := fights[len(fights)-1]
+	if !last.Kill {
+		return false
+	}
+	for _, f := range fights[:len(fights)-1] {
+		if f.EncounterID == last.EncounterID && f.Difficulty == last.Difficulty && !f.Kill {
+			return true
+		}
+	}
+	return false
+}
+
+func highestDifficulty(fights []Fight) int {
+	difficulty := 0
+	for _, f := range fights {
+		difficulty = max(difficulty, f.Difficulty)
+	}
+	return difficulty
+}
+
+func tallyBosses(fights []Fight) (kills, wipes int, bosses []BossTally) {
+	type bossKey struct{ encounterID, difficulty int }
+	idx := make(map[bossKey]int)
+	for _, f := range fights {
+		key := bossKey{f.EncounterID, f.Difficulty}
+		i, ok := idx[key]
+		if !ok {
+			i = len(bosses)
+			idx[key] = i
+			bosses = append(bosses, BossTally{EncounterID: f.EncounterID, Name: f.Name, Difficulty: f.Difficulty})
+		}
+		if f.Kill {
+			kills++
+			bosses[i].Kills++
+		} else {
+			if bosses[i].Wipes == 0 || f.FightPercentage < bosses[i].BestPercent {
+				bosses[i].BestPercent = f.FightPercentage
+			}
+			wipes++
+			bosses[i].Wipes++
+		}
+	}
+	return kills, wipes, bosses
 }
 
 func (c *Client) TopDeathsForReport(ctx context.Context, reportCode string, wipeCutoff int64) (ReportDetails, error) {
-	fights, err := c.GetBossFights(ctx, reportCode)
+	fights, players, err := c.bossFights(ctx, reportCode)
 	if err != nil {
 		return ReportDetails{}, err
 	}
 	if len(fights) == 0 {
-		return ReportDetails{}, nil
+		return ReportDetails{Players: players}, nil
 	}
 
 	var (
@@ -340,11 +579,13 @@ func (c *Client) TopDeathsForReport(ctx context.Context, reportCode string, wipe
 		*list = append(*list, PlayerTop{Name: name, Value: 1})
 	}
 
+	totalCount, pullDeaths := 0, 0
 	for _, f := range fights {
 		events, err := c.getDeathEvents(ctx, reportCode, f.ID, wipeCutoff)
 		if err != nil {
 			return ReportDetails{}, fmt.Errorf("events for fight %d: %w", f.ID, err)
 		}
+		pullDeaths = 0
 
 		firstTaken := false
 		for _, ev := range events {
@@ -353,6 +594,8 @@ func (c *Client) TopDeathsForReport(ctx context.Context, reportCode string, wipe
 				continue
 			}
 			inc(&totalDeaths, totalIdx, name)
+			totalCount++
+			pullDeaths++
 			if !firstTaken {
 				inc(&firstDeaths, firstIdx, name)
 				firstTaken = true
@@ -371,9 +614,26 @@ func (c *Client) TopDeathsForReport(ctx context.Context, reportCode string, wipe
 		firstDeaths = firstDeaths[:N]
 	}
 
+	kills, wipes, bosses := tallyBosses(fights)
+	last := fights[len(fights)-1]
+
 	return ReportDetails{
 		TopDeaths:      totalDeaths,
 		TopFirstDeaths: firstDeaths,
+		Kills:          kills,
+		Wipes:          wipes,
+		Bosses:         bosses,
+		Difficulty:     highestDifficulty(fights),
+		ProgKill:       isProgKill(fights),
+		TotalDeaths:    totalCount,
+		Players:        players,
+		LastPull: Pull{
+			Name:       last.Name,
+			Difficulty: last.Difficulty,
+			Kill:       last.Kill,
+			Percent:    last.FightPercentage,
+			Deaths:     pullDeaths,
+		},
 	}, nil
 }
 
@@ -451,3 +711,400 @@ query($code: String!, $fightId: Int!, $wipeCutoff: Int!, $startTime: Float) {
 
 	return deaths, nil
 }
+
+type tableResp struct {
+	ReportData struct {
+		Report struct {
+			Table struct {
+				Data struct {
+					Entries []struct {
+						Name  string `json:"name"`
+						Total int    `json:"total"`
+					} `json:"entries"`
+				} `json:"data"`
+			} `json:"table"`
+		} `json:"report"`
+	} `json:"reportData"`
+}
+
+func (c *Client) TopDamageForReport(ctx context.Context, reportCode string) ([]PlayerTop, error) {
+	const q = `
+query($code: String!) {
+  reportData {
+    report(code: $code) {
+      table(dataType: DamageDone, killType: Encounters)
+    }
+  }
+}`
+	var out tableResp
+	if err := c.gql(ctx, q, map[string]interface{}{"code": reportCode}, &out); err != nil {
+		return nil, err
+	}
+
+	entries := out.ReportData.Report.Table.Data.Entries
+	top := make([]PlayerTop, 0, len(entries))
+	for _, e := range entries {
+		top = append(top, PlayerTop{Name: e.Name, Value: e.Total})
+	}
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Value > top[j].Value })
+
+	const N = 5
+	if len(top) > N {
+		top = top[:N]
+	}
+	return top, nil
+}
+
+type RateLimit struct {
+	LimitPerHour        float64 `json:"limitPerHour"`
+	PointsSpentThisHour float64 `json:"pointsSpentThisHour"`
+	PointsResetIn       int     `json:"pointsResetIn"`
+}
+
+// RateLimit returns the API points used by the client in the current hour.
+func (c *Client) RateLimit(ctx context.Context) (RateLimit, error) {
+	const q = `query { rateLimitData { limitPerHour pointsSpentThisHour pointsResetIn } }`
+
+	var out struct {
+		RateLimitData RateLimit `json:"rateLimitData"`
+	}
+	if err := c.gql(ctx, q, nil, &out); err != nil {
+		return RateLimit{}, err
+	}
+	return out.RateLimitData, nil
+}
+
+type Guild struct {
+	Name   string
+	Realm  string
+	Region string
+	// MemberName and MemberRealm are a character of the guild, empty if the log site knows none.
+	MemberName  string
+	MemberRealm string
+}
+
+// Guild returns the name, realm slug and region slug of the guild, and one of its members.
+func (c *Client) Guild(ctx context.Context, guildId int64) (Guild, error) {
+	const q = `
+query($id: Int!) {
+  guildData {
+    guild(id: $id) {
+      name
+      server {
+        slug
+        region {
+          slug
+        }
+      }
+      members(limit: 1) {
+        data {
+          name
+          server {
+            slug
+          }
+        }
+      }
+    }
+  }
+}`
+
+	var out struct {
+		GuildData struct {
+			Guild *struct {
+				Name   string `json:"name"`
+				Server struct {
+					Slug   string `json:"slug"`
+					Region struct {
+						Slug string `json:"slug"`
+					} `json:"region"`
+				} `json:"server"`
+				Members struct {
+					Data []struct {
+						Name   string `json:"name"`
+						Server struct {
+							Slug string `json:"slug"`
+						} `json:"server"`
+					} `json:"data"`
+				} `json:"members"`
+			} `json:"guild"`
+		} `json:"guildData"`
+	}
+	if err := c.gql(ctx, q, map[string]interface{}{"id": guildId}, &out); err != nil {
+		return Guild{}, err
+	}
+	g := out.GuildData.Guild
+	if g == nil {
+		return Guild{}, fmt.Errorf("guild %d not found", guildId)
+	}
+	guild := Guild{Name: g.Name, Realm: g.Server.Slug, Region: g.Server.Region.Slug}
+	if len(g.Members.Data) > 0 {
+		guild.MemberName = g.Members.Data[0].Name
+		guild.MemberRealm = g.Members.Data[0].Server.Slug
+	}
+	return guild, nil
+}
+
+// ReportPlayers returns the names of the players present in the report.
+func (c *Client) ReportPlayers(ctx context.Context, reportCode string) ([]string, error) {
+	const q = `
+query($code: String!) {
+  reportData {
+    report(code: $code) {
+      masterData {
+        actors(type: "Player") {
+          name
+        }
+      }
+    }
+  }
+}`
+
+	var out struct {
+		ReportData struct {
+			Report struct {
+				MasterData struct {
+					Actors []struct {
+						Name string `json:"name"`
+					} `json:"actors"`
+				} `json:"masterData"`
+			} `json:"report"`
+		} `json:"reportData"`
+	}
+	if err := c.gql(ctx, q, map[string]interface{}{"code": reportCode}, &out); err != nil {
+		return nil, err
+	}
+	players := make([]string, 0, len(out.ReportData.Report.MasterData.Actors))
+	for _, a := range out.ReportData.Report.MasterData.Actors {
+		players = append(players, a.Name)
+	}
+	return players, nil
+}
+
+type PlayerStats struct {
+	Name   string
+	Class  string
+	Deaths int
+	// Parse is the average rank percent over the ranked kills, zero when the player has none.
+	Parse float64
+}
+
+// ReportPlayerStats returns the deaths up to the wipe cutoff and the average parse of every player present in the report.
+func (c *Client) ReportPlayerStats(ctx context.Context, reportCode string, wipeCutoff int64) ([]PlayerStats, error) {
+	const q = `
+query($code: String!, $wipeCutoff: Int!) {
+  reportData {
+    report(code: $code) {
+      masterData {
+        actors(type: "Player") {
+          name
+          subType
+        }
+      }
+      deaths: table(dataType: Deaths, killType: Encounters, wipeCutoff: $wipeCutoff)
+      rankings
+    }
+  }
+}`
+
+	type ranked struct {
+		Characters []struct {
+			Name        string  `json:"name"`
+			RankPercent float64 `json:"rankPercent"`
+		} `json:"characters"`
+	}
+	var out struct {
+		ReportData struct {
+			Report struct {
+				MasterData struct {
+					Actors []struct {
+						Name    string `json:"name"`
+						SubType string `json:"subType"`
+					} `json:"actors"`
+				} `json:"masterData"`
+				Deaths struct {
+					Data struct {
+						Entries []struct {
+							Name string `json:"name"`
+						} `json:"entries"`
+					} `json:"data"`
+				} `json:"deaths"`
+				Rankings struct {
+					Data []struct {
+						Roles struct {
+							Tanks   ranked `json:"tanks"`
+							Healers ranked `json:"healers"`
+							DPS     ranked `json:"dps"`
+						} `json:"roles"`
+					} `json:"data"`
+				} `json:"rankings"`
+			} `json:"report"`
+		} `json:"reportData"`
+	}
+	if err := c.gql(ctx, q, map[string]interface{}{"code": reportCode, "wipeCutoff": wipeCutoff}, &out); err != nil {
+		return nil, err
+	}
+
+	report := out.ReportData.Report
+	stats := make([]PlayerStats, 0, len(report.MasterData.Actors))
+	idx := make(map[string]int)
+	for _, a := range report.MasterData.Actors {
+		if _, ok := idx[a.Name]; ok {
+			continue
+		}
+		idx[a.Name] = len(stats)
+		stats = append(stats, PlayerStats{Name: a.Name, Class: a.SubType})
+	}
+	for _, e := range report.Deaths.Data.Entries {
+		if i, ok := idx[e.Name]; ok {
+			stats[i].Deaths++
+		}
+	}
+	counts := make([]int, len(stats))
+	for _, fight := range report.Rankings.Data {
+		for _, role := range []ranked{fight.Roles.Tanks, fight.Roles.Healers, fight.Roles.DPS} {
+			for _, ch := range role.Characters {
+				if i, ok := idx[ch.Name]; ok {
+					stats[i].Parse += ch.RankPercent
+					counts[i]++
+				}
+			}
+		}
+	}
+	for i := range stats {
+		if counts[i] > 0 {
+			stats[i].Parse /= float64(counts[i])
+		}
+	}
+	return stats, nil
+}
+
+type AbilityEvent struct {
+	Type          string `json:"type"`
+	AbilityGameID int    `json:"abilityGameID"`
+	Amount        int    `json:"amount"`
+	Absorbed      int    `json:"absorbed"`
+	Target        struct {
+		Name string `json:"name"`
+	} `json:"target"`
+}
+
+// AbilityEvents returns the events of the fight matching the filter expression, up to the wipe cutoff.
+func (c *Client) AbilityEvents(ctx context.Context, reportCode string, fightId int, wipeCutoff int64, filter string) ([]AbilityEvent, error) {
+	const q = `
+query($code: String!, $fightId: Int!, $wipeCutoff: Int!, $filter: String!, $startTime: Float) {
+  reportData {
+    report(code: $code) {
+      events(
+        dataType: All
+        fightIDs: [$fightId]
+        filterExpression: $filter
+        limit: 10000
+        useAbilityIDs: true
+        useActorIDs: false
+        wipeCutoff: $wipeCutoff
+        startTime: $startTime
+      ) {
+        data
+        nextPageTimestamp
+      }
+    }
+  }
+}`
+
+	var (
+		events    []AbilityEvent
+		pageCount int
+		maxPages  = 10
+	)
+	vars := map[string]interface{}{
+		"code":       reportCode,
+		"fightId":    fightId,
+		"wipeCutoff": wipeCutoff,
+		"filter":     filter,
+	}
+	for {
+		var out eventsPage
+		if err := c.gql(ctx, q, vars, &out); err != nil {
+			return nil, err
+		}
+		evs := out.ReportData.Report.Events
+		for _, raw := range evs.Data {
+			var ev AbilityEvent
+			if err := json.Unmarshal(raw, &ev); err != nil {
+				slog.Warn("failed to unmarshal AbilityEvent", "error", err)
+				continue
+			}
+			events = append(events, ev)
+		}
+		if evs.NextPageTimestamp == nil {
+			break
+		}
+		vars["startTime"] = *evs.NextPageTimestamp
+
+		pageCount++
+		if pageCount >= maxPages {
+			slog.Warn("pagination aborted: exceeded max pages", "maxPages", maxPages)
+			break
+		}
+	}
+	return events, nil
+}
+
+type Actor struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// FightPlayers returns the name of the fight's encounter and the players who took part in it.
+func (c *Client) FightPlayers(ctx context.Context, reportCode string, fightId int) (string, []Actor, error) {
+	const q = `
+query($code: String!, $fightId: Int!) {
+  reportData {
+    report(code: $code) {
+      fights(fightIDs: [$fightId]) {
+        name
+        friendlyPlayers
+      }
+      masterData {
+        actors(type: "Player") {
+          id
+          name
+        }
+      }
+    }
+  }
+}`
+
+	var out struct {
+		ReportData struct {
+			Report struct {
+				Fights []struct {
+					Name            string `json:"name"`
+					FriendlyPlayers []int  `json:"friendlyPlayers"`
+				} `json:"fights"`
+				MasterData struct {
+					Actors []Actor `json:"actors"`
+				} `json:"masterData"`
+			} `json:"report"`
+		} `json:"reportData"`
+	}
+	if err := c.gql(ctx, q, map[string]interface{}{"code": reportCode, "fightId": fightId}, &out); err != nil {
+		return "", nil, err
+	}
+	report := out.ReportData.Report
+	if len(report.Fights) == 0 {
+		return "", nil, fmt.Errorf("fight %d not found in report %v", fightId, reportCode)
+	}
+	fight := report.Fights[0]
+	names := make(map[int]string, len(report.MasterData.Actors))
+	for _, a := range report.MasterData.Actors {
+		names[a.ID] = a.Name
+	}
+	players := make([]Actor, 0, len(fight.FriendlyPlayers))
+	for _, id := range fight.FriendlyPlayers {
+		if name, ok := names[id]; ok {
+			players = append(players, Actor{ID: id, Name: name})
+		}
+	}
+	return fight.Name, players, nil
+}
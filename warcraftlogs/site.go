@@ -0,0 +1,68 @@
+package warcraftlogs
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Site is one of the log sites sharing the Warcraft Logs v2 API, each with its own API clients.
+type Site struct {
+	Id   string
+	Name string
+	Host string
+	// raidSize is the group size of the content watched on the site.
+	raidSize int
+}
+
+var (
+	Warcraft = Site{Id: "warcraftlogs", Name: "Warcraft Logs", Host: "www.warcraftlogs.com", raidSize: 20}
+	FFLogs   = Site{Id: "fflogs", Name: "FF Logs", Host: "www.fflogs.com", raidSize: 8}
+	ESOLogs  = Site{Id: "esologs", Name: "ESO Logs", Host: "www.esologs.com", raidSize: 12}
+)
+
+var Sites = []Site{Warcraft, FFLogs, ESOLogs}
+
+// SiteById returns the site with the id, servers configured before other sites were supported have none and use Warcraft Logs.
+func SiteById(id string) (Site, bool) {
+	if id == "" {
+		return Warcraft, true
+	}
+	i := slices.IndexFunc(Sites, func(s Site) bool { return s.Id == id })
+	if i < 0 {
+		return Site{}, false
+	}
+	return Sites[i], true
+}
+
+func (s Site) ReportURL(code string) string {
+	return fmt.Sprintf("https://%v/reports/%v", s.Host, code)
+}
+
+func (s Site) GuildURL(id int64) string {
+	return fmt.Sprintf("https://%v/guild/id/%v", s.Host, id)
+}
+
+func (s Site) tokenURL() string {
+	return "https://" + s.Host + "/oauth/token"
+}
+
+func (s Site) graphQLURL() string {
+	return "https://" + s.Host + "/api/v2/client"
+}
+
+// IsRaid reports whether the zone is raid content of the site: mythic 20 man raids on Warcraft Logs,
+// 8 man savage and ultimate fights on FF Logs and 12 man trials on ESO Logs.
+func (s Site) IsRaid(zone Zone) bool {
+	for _, difficulty := range zone.Difficulties {
+		if s == Warcraft {
+			if difficulty.Name == "Mythic" && len(difficulty.Sizes) == 1 && difficulty.Sizes[0] == s.raidSize {
+				return true
+			}
+			continue
+		}
+		if slices.Contains(difficulty.Sizes, s.raidSize) {
+			return true
+		}
+	}
+	return false
+}
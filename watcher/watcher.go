@@ -6,9 +6,15 @@ import (
 	"log/slog"
 	"math/rand/v2"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"bot/calendar"
+	"bot/errreport"
+	"bot/metrics"
 	"bot/storage"
 	"bot/warcraftlogs"
 
@@ -26,6 +32,22 @@ type LogEndEvent struct {
 	Id     string
 }
 
+// RaidAnnounceEvent is sent once per calendar event and watch loop when the raid is about to start, a loop
+// started again after a settings change sends it again and the handler skips raids it already announced.
+type RaidAnnounceEvent struct {
+	Server storage.Server
+	UID    string
+	Title  string
+	Start  time.Time
+}
+
+// ScheduleEvent carries the upcoming calendar events of a server syncing them to discord scheduled events,
+// it is sent every time the calendar is checked.
+type ScheduleEvent struct {
+	Server storage.Server
+	Events []calendar.Event
+}
+
 type TopDude struct {
 	Name  string
 	Value string
@@ -38,31 +60,194 @@ type StatsEvent struct {
 	Zone          string
 	URL           string
 	Live          bool
+	Ended         bool
 	TopDPS        []warcraftlogs.PlayerTop
 	TopHPS        []warcraftlogs.PlayerTop
 	TopDeath      []warcraftlogs.PlayerTop
 	TopFirstDeath []warcraftlogs.PlayerTop
+	Kills         int
+	Wipes         int
+	TotalDeaths   int
+	LastPull      warcraftlogs.Pull
+	Bosses        []warcraftlogs.BossTally
+	Difficulty    int
+	ProgKill      bool
 	StartedBy     string
 	StartedAt     time.Time
 	LastUpload    time.Time
+	PollInterval  time.Duration
+	NextRefresh   time.Time
+	Players       []string
+}
+
+var defaultPollInterval atomic.Int64
+
+func init() {
+	defaultPollInterval.Store(int64(1 * time.Minute))
+}
+
+// SetDefaultPollInterval changes the poll interval of servers without their own, running loops pick it up on the next poll.
+func SetDefaultPollInterval(d time.Duration) {
+	defaultPollInterval.Store(int64(d))
+}
+
+func PollInterval(server storage.Server) time.Duration {
+	if server.PollInterval <= 0 {
+		return time.Duration(defaultPollInterval.Load())
+	}
+	return time.Duration(server.PollInterval) * time.Minute
 }
 
 type Watcher struct {
-	wlClient *warcraftlogs.Client
-	handler  func(se StatsEvent)
-	watched  sync.Map
+	clients   map[string]*warcraftlogs.Client
+	calClient *calendar.Client
+	handler   func(se StatsEvent)
+	announcer func(ae RaidAnnounceEvent)
+	scheduler func(se ScheduleEvent)
+	watched   sync.Map
+	failures  *errreport.Tracker
+	loops     sync.WaitGroup
+	stopped   atomic.Bool
 }
 
-func New(wlClient *warcraftlogs.Client) *Watcher {
-	return &Watcher{wlClient: wlClient}
+type Status struct {
+	ServerId  string    `json:"server_id"`
+	Since     time.Time `json:"since"`
+	LastPoll  time.Time `json:"last_poll"`
+	NextPoll  time.Time `json:"next_poll"`
+	Reports   int       `json:"reports"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+type watchEntry struct {
+	cancel  context.CancelFunc
+	refresh chan struct{}
+	// server holds the settings the loop polls with, Update swaps them
+	server atomic.Pointer[storage.Server]
+
+	mu     sync.Mutex
+	status Status
+}
+
+func (e *watchEntry) update(fn func(status *Status)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn(&e.status)
+}
+
+func (e *watchEntry) snapshot() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status
+}
+
+// New creates a watcher polling each server on the site it is configured for, clients are keyed by site id.
+func New(clients map[string]*warcraftlogs.Client, calClient *calendar.Client) *Watcher {
+	return &Watcher{clients: clients, calClient: calClient, failures: errreport.NewTracker(5)}
+}
+
+// Client returns the API client of the site the server is configured for.
+func (w *Watcher) Client(server storage.Server) (*warcraftlogs.Client, error) {
+	site, ok := warcraftlogs.SiteById(server.Site)
+	if !ok {
+		return nil, fmt.Errorf("unknown site %q", server.Site)
+	}
+	client, ok := w.clients[site.Id]
+	if !ok {
+		return nil, fmt.Errorf("%v is not configured", site.Name)
+	}
+	return client, nil
 }
 
 func (w *Watcher) Watch(server storage.Server) {
+	if w.stopped.Load() {
+		return
+	}
 	ctx, cancel := context.WithCancel(context.Background())
-	_, isLoaded := w.watched.LoadOrStore(server.ServerId, cancel)
+	entry := &watchEntry{
+		cancel:  cancel,
+		refresh: make(chan struct{}, 1),
+		status:  Status{ServerId: server.ServerId, Since: time.Now()},
+	}
+	entry.server.Store(&server)
+	_, isLoaded := w.watched.LoadOrStore(server.ServerId, entry)
 	if !isLoaded {
-		go w.watchLoop(ctx, server)
+		w.loops.Add(1)
+		go func() {
+			defer w.loops.Done()
+			for w.runLoop(ctx, server, entry) {
+				slog.Warn("restarting watch loop after panic", "server", server.ServerId)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(loopRestartDelay):
+				}
+			}
+		}()
+	} else {
+		cancel()
+	}
+}
+
+// Update hands new settings to the watch loop of the server, they are used from its next poll on and the reports
+// the loop has seen are kept. It reports false if the server is not watched. A new guild or site needs Unwatch and
+// Watch instead.
+func (w *Watcher) Update(server storage.Server) bool {
+	entry, isKnown := w.watched.Load(server.ServerId)
+	if !isKnown {
+		return false
+	}
+	entry.(*watchEntry).server.Store(&server)
+	return true
+}
+
+// Refresh makes the watch loop of the server poll immediately, it reports false if the server is not watched.
+func (w *Watcher) Refresh(serverId string) bool {
+	entry, isKnown := w.watched.Load(serverId)
+	if !isKnown {
+		return false
+	}
+	select {
+	case entry.(*watchEntry).refresh <- struct{}{}:
+	default:
 	}
+	return true
+}
+
+func (w *Watcher) Status(serverId string) (Status, bool) {
+	entry, isKnown := w.watched.Load(serverId)
+	if !isKnown {
+		return Status{}, false
+	}
+	return entry.(*watchEntry).snapshot(), true
+}
+
+func (w *Watcher) Statuses() []Status {
+	var statuses []Status
+	w.watched.Range(func(_, entry any) bool {
+		statuses = append(statuses, entry.(*watchEntry).snapshot())
+		return true
+	})
+	slices.SortFunc(statuses, func(a, b Status) int { return strings.Compare(a.ServerId, b.ServerId) })
+	return statuses
+}
+
+const loopRestartDelay = 10 * time.Second
+
+// runLoop runs the watch loop and reports whether it was aborted by a panic and should be restarted.
+func (w *Watcher) runLoop(ctx context.Context, server storage.Server, entry *watchEntry) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			metrics.Errors.WithLabelValues("panic").Inc()
+			errreport.ReportPanic(ctx, r, map[string]string{
+				"component": "watcher",
+				"server":    server.ServerId,
+			})
+			panicked = ctx.Err() == nil
+		}
+	}()
+	w.watchLoop(ctx, server, entry)
+	return false
 }
 
 type CachedReport struct {
@@ -71,14 +256,42 @@ type CachedReport struct {
 	isLive  bool
 }
 
-func (w *Watcher) watchLoop(ctx context.Context, server storage.Server) {
+func (w *Watcher) watchLoop(ctx context.Context, server storage.Server, entry *watchEntry) {
 	logger := slog.With("server", server.ServerId)
 
+	metrics.ActiveWatchLoops.Inc()
+	defer metrics.ActiveWatchLoops.Dec()
+
 	reportsCache := ttlcache.New[string, CachedReport](
 		ttlcache.WithTTL[string, CachedReport](1 * time.Hour),
 	)
 	go reportsCache.Start()
 
+	announced := make(map[string]time.Time)
+	poll := func() {
+		server := *entry.server.Load()
+		next := time.Now().Add(w.interval(ctx, logger, server, announced))
+		reports, err := w.checkChanges(ctx, logger, server, reportsCache, next)
+		if err != nil {
+			w.failures.Fail(ctx, server.ServerId, err, map[string]string{
+				"component": "watcher",
+				"server":    server.ServerId,
+				"guild":     strconv.FormatInt(server.WlGuildId, 10),
+			})
+		} else {
+			w.failures.Succeed(server.ServerId)
+		}
+		entry.update(func(status *Status) {
+			status.LastPoll = time.Now()
+			status.NextPoll = next
+			status.Reports = reports
+			status.LastError = ""
+			if err != nil {
+				status.LastError = err.Error()
+			}
+		})
+	}
+
 	jitter := rand.IntN(10000)
 	after := time.After(time.Duration(jitter) * time.Millisecond)
 	for {
@@ -86,27 +299,92 @@ func (w *Watcher) watchLoop(ctx context.Context, server storage.Server) {
 		case <-ctx.Done():
 			logger.Info("watch loop is stopped")
 			return
+		case <-entry.refresh:
+			logger.Info("forced refresh")
+			poll()
+			after = time.After(time.Until(entry.snapshot().NextPoll))
 		case <-after:
-			w.checkChanges(ctx, logger, server, reportsCache)
-			after = time.After(1 * time.Minute)
+			poll()
+			after = time.After(time.Until(entry.snapshot().NextPoll))
 		}
 	}
 }
 
-func (w *Watcher) checkChanges(ctx context.Context, logger *slog.Logger, server storage.Server, reportsCache *ttlcache.Cache[string, CachedReport]) {
+const (
+	raidLeadTime     = 30 * time.Minute
+	raidTrailTime    = time.Hour
+	idlePollInterval = 15 * time.Minute
+)
+
+// interval returns the time until the next poll. Servers with a calendar are polled at their poll interval
+// from raidLeadTime before a raid until raidTrailTime after it and every idlePollInterval otherwise,
+// entering the window announces the raid once.
+func (w *Watcher) interval(ctx context.Context, logger *slog.Logger, server storage.Server, announced map[string]time.Time) time.Duration {
+	regular := PollInterval(server)
+	if server.CalendarURL == "" {
+		return regular
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	events, err := w.calClient.Events(ctx, server.CalendarURL)
+	if err != nil {
+		logger.Warn("error loading calendar", "error", err)
+		return regular
+	}
+	if server.ScheduledEvents && w.scheduler != nil {
+		w.scheduler(ScheduleEvent{Server: server, Events: events})
+	}
+
+	now := time.Now()
+	for uid, start := range announced {
+		if now.Sub(start) > 24*time.Hour {
+			delete(announced, uid)
+		}
+	}
+	next := max(idlePollInterval, regular)
+	for _, e := range events {
+		windowStart := e.Start.Add(-raidLeadTime)
+		if now.Before(windowStart) {
+			return min(next, windowStart.Sub(now))
+		}
+		if now.Before(e.End.Add(raidTrailTime)) {
+			if _, ok := announced[e.UID]; !ok && server.CalendarAnnounce && now.Before(e.Start) && w.announcer != nil {
+				announced[e.UID] = e.Start
+				logger.Info("announcing raid", "event", e.UID, "start", e.Start)
+				w.announcer(RaidAnnounceEvent{Server: server, UID: e.UID, Title: e.Summary, Start: e.Start})
+			}
+			return regular
+		}
+	}
+	return next
+}
+
+func (w *Watcher) checkChanges(ctx context.Context, logger *slog.Logger, server storage.Server, reportsCache *ttlcache.Cache[string, CachedReport], nextRefresh time.Time) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
 	start := time.Now()
-	reports, err := w.wlClient.FindReports(ctx, server.WlGuildId, time.Now().Add(-12*time.Hour))
+	defer func() {
+		metrics.PollDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	wlClient, err := w.Client(server)
 	if err != nil {
+		logger.Error("error selecting log site", "error", err)
+		return 0, err
+	}
+	reports, err := wlClient.FindReports(ctx, server.WlGuildId, time.Now().Add(-12*time.Hour))
+	if err != nil {
+		metrics.Errors.WithLabelValues("watcher").Inc()
 		logger.Error("error loading guild reports", slog.Int64("guild", server.WlGuildId), "error", err)
-		return
+		return 0, err
 	}
 
-	reports = deleteNonRaid(reports)
+	reports = deleteNonRaid(wlClient.Site(), reports)
 	logger.Info("loaded reports", "len", len(reports), "duration", time.Since(start).Truncate(time.Millisecond))
 
+	var lastErr error
+
 	for _, report := range reports {
 		isOutdated := time.Since(time.UnixMilli(report.EndTime)) > 15*time.Minute
 
@@ -125,14 +403,16 @@ func (w *Watcher) checkChanges(ctx context.Context, logger *slog.Logger, server
 				logger.Info("old report, skipping", "report", report.Code)
 			default:
 				start := time.Now()
-				details, err := w.wlClient.TopDeathsForReport(ctx, report.Code, server.WipeCutoff)
+				details, err := wlClient.TopDeathsForReport(ctx, report.Code, server.WipeCutoff)
 				if err != nil {
+					metrics.Errors.WithLabelValues("watcher").Inc()
 					logger.Error("error fetching report details", "report", report.Code, "error", err)
+					lastErr = err
 					continue
 				}
 				logger.Info("loaded report details", "report", report.Code, "duration", time.Since(start).Truncate(time.Millisecond))
 				logger.Info("new live report, sending updates", "report", report.Code)
-				w.sendUpdate(ctx, server, true, report, details)
+				w.sendUpdate(ctx, logger, wlClient, server, true, false, report, details, nextRefresh)
 				lr := CachedReport{code: report.Code, endTime: report.EndTime, isLive: true}
 				reportsCache.Set(report.Code, lr, ttlcache.DefaultTTL)
 			}
@@ -140,26 +420,30 @@ func (w *Watcher) checkChanges(ctx context.Context, logger *slog.Logger, server
 			switch {
 			case cachedReport.endTime != report.EndTime:
 				start := time.Now()
-				details, err := w.wlClient.TopDeathsForReport(ctx, report.Code, server.WipeCutoff)
+				details, err := wlClient.TopDeathsForReport(ctx, report.Code, server.WipeCutoff)
 				if err != nil {
+					metrics.Errors.WithLabelValues("watcher").Inc()
 					logger.Error("error fetching report details", "report", report.Code, "error", err)
+					lastErr = err
 					continue
 				}
 				logger.Info("loaded report details", "report", report.Code, "duration", time.Since(start).Truncate(time.Millisecond))
 				logger.Info("report has changes, sending updates", "report", report.Code)
-				w.sendUpdate(ctx, server, !isOutdated, report, details)
+				w.sendUpdate(ctx, logger, wlClient, server, !isOutdated, cachedReport.isLive && isOutdated, report, details, nextRefresh)
 				lr := CachedReport{code: report.Code, endTime: report.EndTime, isLive: !isOutdated}
 				reportsCache.Set(report.Code, lr, ttlcache.DefaultTTL)
 			case cachedReport.isLive && isOutdated:
 				start := time.Now()
-				details, err := w.wlClient.TopDeathsForReport(ctx, report.Code, server.WipeCutoff)
+				details, err := wlClient.TopDeathsForReport(ctx, report.Code, server.WipeCutoff)
 				if err != nil {
+					metrics.Errors.WithLabelValues("watcher").Inc()
 					logger.Error("error fetching report details", "report", report.Code, "error", err)
+					lastErr = err
 					continue
 				}
 				logger.Info("loaded report details", "report", report.Code, "duration", time.Since(start).Truncate(time.Millisecond))
 				logger.Info("report went offline, sending updates", "report", report.Code)
-				w.sendUpdate(ctx, server, false, report, details)
+				w.sendUpdate(ctx, logger, wlClient, server, false, true, report, details, nextRefresh)
 				lr := CachedReport{code: report.Code, endTime: report.EndTime, isLive: false}
 				reportsCache.Set(report.Code, lr, ttlcache.DefaultTTL)
 			default:
@@ -167,48 +451,118 @@ func (w *Watcher) checkChanges(ctx context.Context, logger *slog.Logger, server
 			}
 		}
 	}
+	return len(reports), lastErr
 }
 
-func (w *Watcher) sendUpdate(ctx context.Context, server storage.Server, isLive bool, report warcraftlogs.Report, details warcraftlogs.ReportDetails) {
+func (w *Watcher) sendUpdate(ctx context.Context, logger *slog.Logger, wlClient *warcraftlogs.Client, server storage.Server, isLive, isEnded bool, report warcraftlogs.Report, details warcraftlogs.ReportDetails, nextRefresh time.Time) {
 	select {
 	case <-ctx.Done():
 		return
 	default:
 	}
 
-	w.handler(StatsEvent{
+	var topDPS []warcraftlogs.PlayerTop
+	if isEnded {
+		var err error
+		topDPS, err = wlClient.TopDamageForReport(ctx, report.Code)
+		if err != nil {
+			logger.Error("error fetching report damage", "report", report.Code, "error", err)
+		}
+	}
+
+	w.handler(newStatsEvent(wlClient.Site(), server, isLive, isEnded, report, details, topDPS, nextRefresh))
+}
+
+// ReportStats returns the stats of any report on the log site of the server, outside of the watch loop the report
+// is never live and its damage is not loaded.
+func (w *Watcher) ReportStats(ctx context.Context, server storage.Server, code string) (StatsEvent, error) {
+	wlClient, err := w.Client(server)
+	if err != nil {
+		return StatsEvent{}, err
+	}
+	report, err := wlClient.Report(ctx, code)
+	if err != nil {
+		return StatsEvent{}, err
+	}
+	details, err := wlClient.TopDeathsForReport(ctx, code, server.WipeCutoff)
+	if err != nil {
+		return StatsEvent{}, err
+	}
+	return newStatsEvent(wlClient.Site(), server, false, false, report, details, nil, time.Time{}), nil
+}
+
+func newStatsEvent(site warcraftlogs.Site, server storage.Server, isLive, isEnded bool, report warcraftlogs.Report, details warcraftlogs.ReportDetails, topDPS []warcraftlogs.PlayerTop, nextRefresh time.Time) StatsEvent {
+	return StatsEvent{
 		Server:        server,
 		ReportId:      report.Code,
 		Title:         report.Title,
 		Zone:          report.Zone.Name,
-		URL:           fmt.Sprintf("https://www.warcraftlogs.com/reports/%v", report.Code),
+		URL:           site.ReportURL(report.Code),
 		Live:          isLive,
+		Ended:         isEnded,
+		TopDPS:        topDPS,
+		TotalDeaths:   details.TotalDeaths,
+		LastPull:      details.LastPull,
 		TopDeath:      details.TopDeaths,
 		TopFirstDeath: details.TopFirstDeaths,
+		Kills:         details.Kills,
+		Wipes:         details.Wipes,
+		Bosses:        details.Bosses,
+		Difficulty:    details.Difficulty,
+		ProgKill:      details.ProgKill,
 		StartedBy:     report.Owner.Name,
 		StartedAt:     time.UnixMilli(report.StartTime),
 		LastUpload:    time.UnixMilli(report.EndTime),
-	})
+		PollInterval:  PollInterval(server),
+		NextRefresh:   nextRefresh,
+		Players:       details.Players,
+	}
 }
 
-func deleteNonRaid(reports []warcraftlogs.Report) []warcraftlogs.Report {
+func deleteNonRaid(site warcraftlogs.Site, reports []warcraftlogs.Report) []warcraftlogs.Report {
 	return slices.DeleteFunc(reports, func(report warcraftlogs.Report) bool {
-		for _, difficulty := range report.Zone.Difficulties {
-			if difficulty.Name == "Mythic" && len(difficulty.Sizes) == 1 && difficulty.Sizes[0] == 20 {
-				return false
-			}
-		}
-		return true
+		return !site.IsRaid(report.Zone)
 	})
 }
 
 func (w *Watcher) Unwatch(serverId string) {
-	cancel, isKnown := w.watched.LoadAndDelete(serverId)
+	entry, isKnown := w.watched.LoadAndDelete(serverId)
 	if isKnown {
-		cancel.(context.CancelFunc)()
+		entry.(*watchEntry).cancel()
+	}
+}
+
+// Stop cancels every watch loop and waits until they return, including updates being delivered to the handler.
+// Watch is a no-op afterwards.
+func (w *Watcher) Stop(ctx context.Context) error {
+	w.stopped.Store(true)
+	w.watched.Range(func(serverId, entry any) bool {
+		w.watched.Delete(serverId)
+		entry.(*watchEntry).cancel()
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		w.loops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
 func (w *Watcher) OnUpdate(handler func(se StatsEvent)) {
 	w.handler = handler
 }
+
+func (w *Watcher) OnAnnounce(handler func(ae RaidAnnounceEvent)) {
+	w.announcer = handler
+}
+
+func (w *Watcher) OnSchedule(handler func(se ScheduleEvent)) {
+	w.scheduler = handler
+}